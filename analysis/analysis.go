@@ -0,0 +1,288 @@
+// analysis.go - HORST key pair usage analysis
+
+// Package analysis tracks how often each HORST key pair of a SPHINCS-256 key
+// has been used, and estimates the remaining security level.
+//
+// SPHINCS-256 signs every message with a HORST few-time signature key pair
+// picked pseudorandomly out of 2^60, and each additional signature made with
+// the same HORST key pair reveals more of its secret key.  Following the
+// SPHINCS paper (section 3.1), after r signatures with one HORST key pair, a
+// forgery for a random message hash succeeds with probability at most
+// (rk/t)^k, where k = 32 and t = 2^16.  Since the key pair used for the
+// forgery is also random, the overall success probability after q signatures
+// is at most
+//
+//	sum_r binom(q, r) (1 - 2^-h)^(q-r) 2^(-hr) min(1, (rk/t)^k)
+//
+// where h = 60 is the hypertree height.
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/yawning/sphincs256"
+	"github.com/yawning/sphincs256/horst"
+)
+
+const (
+	// DefaultBudget is the default signature budget of a key, the 2^50
+	// signatures assumed by the security analysis of SPHINCS-256.
+	DefaultBudget = 1 << 50
+
+	// DefaultMinSecurityBits is the default security level, in bits, below
+	// which a single HORST key pair is considered overused.
+	DefaultMinSecurityBits = 128
+
+	// maxUses bounds the sum over the number of uses r when computing the
+	// forgery bound.  The expected number of uses of a HORST key pair is
+	// q/2^60 <= 16, so the terms past this are negligible.
+	maxUses = 4096
+)
+
+// WarningKind is the kind of a Warning.
+type WarningKind int
+
+const (
+	// BudgetApproaching is issued once when half of the signature budget has
+	// been used.
+	BudgetApproaching WarningKind = iota
+
+	// BudgetExceeded is issued once when the signature budget has been
+	// exceeded.
+	BudgetExceeded
+
+	// InstanceOverused is issued each time a HORST key pair whose security
+	// level is below the minimum is used again.
+	InstanceOverused
+)
+
+// Warning is a warning about the usage of a key, returned by Tracker.Add.
+type Warning struct {
+	Kind WarningKind
+
+	// Signatures is the number of signatures tracked so far.
+	Signatures uint64
+
+	// LeafIndex and Uses are the leaf index and number of uses of the HORST
+	// key pair, for InstanceOverused.
+	LeafIndex uint64
+	Uses      int
+
+	// SecurityBits is the security level of the HORST key pair, for
+	// InstanceOverused.
+	SecurityBits float64
+}
+
+func (w *Warning) String() string {
+	switch w.Kind {
+	case BudgetApproaching:
+		return fmt.Sprintf("analysis: %d signatures, approaching the signature budget", w.Signatures)
+	case BudgetExceeded:
+		return fmt.Sprintf("analysis: %d signatures, signature budget exceeded", w.Signatures)
+	case InstanceOverused:
+		return fmt.Sprintf("analysis: HORST key pair %#x used %d times, %.1f bits of security", w.LeafIndex, w.Uses, w.SecurityBits)
+	default:
+		return fmt.Sprintf("analysis: unknown warning %d", int(w.Kind))
+	}
+}
+
+// Report is a summary of the usage of a key.
+type Report struct {
+	// Signatures is the number of signatures tracked.
+	Signatures uint64
+
+	// Budget is the signature budget, and BudgetUsed is the fraction of it
+	// used so far.
+	Budget     uint64
+	BudgetUsed float64
+
+	// Instances is the number of distinct HORST key pairs used.
+	Instances int
+
+	// MaxUses is the number of uses of the most used HORST key pair.
+	MaxUses int
+
+	// Histogram maps a number of uses to the number of HORST key pairs used
+	// that many times.
+	Histogram map[int]uint64
+
+	// WorstInstanceBits is the security level of the most used HORST key
+	// pair, -log2((MaxUses*k/t)^k).
+	WorstInstanceBits float64
+
+	// ObservedBits is the security level against a forgery, given the actual
+	// usage of the HORST key pairs.
+	ObservedBits float64
+
+	// BoundBits is the security level against a forgery after Signatures
+	// signatures as per the SPHINCS paper, which does not depend on which
+	// HORST key pairs were actually used.
+	BoundBits float64
+}
+
+// Tracker tracks the HORST key pairs used by a stream of signatures made
+// with one key.  It keeps a counter per HORST key pair used, so memory use
+// grows linearly with the number of signatures.  The zero value is ready for
+// use, with the defaults.  It is safe for concurrent use.
+type Tracker struct {
+	// Budget is the signature budget of the key.  If 0, DefaultBudget is
+	// used.
+	Budget uint64
+
+	// MinSecurityBits is the security level of a single HORST key pair
+	// below which InstanceOverused warnings are issued.  If 0,
+	// DefaultMinSecurityBits is used.
+	MinSecurityBits float64
+
+	mu         sync.Mutex
+	uses       map[uint64]int
+	histogram  map[int]uint64
+	signatures uint64
+	maxUses    int
+}
+
+func (t *Tracker) budget() uint64 {
+	if t.Budget == 0 {
+		return DefaultBudget
+	}
+	return t.Budget
+}
+
+func (t *Tracker) minSecurityBits() float64 {
+	if t.MinSecurityBits == 0 {
+		return DefaultMinSecurityBits
+	}
+	return t.MinSecurityBits
+}
+
+// Add tracks a signature, and returns the warnings about the key's usage
+// that it triggers, if any.  The signature is not verified.
+func (t *Tracker) Add(signature *[sphincs256.SignatureSize]byte) []Warning {
+	return t.AddLeafIndex(sphincs256.LeafIndex(signature))
+}
+
+// AddLeafIndex tracks a signature given its leaf index, as returned by
+// sphincs256.LeafIndex, and returns the warnings about the key's usage that
+// it triggers, if any.
+func (t *Tracker) AddLeafIndex(leafidx uint64) []Warning {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.uses == nil {
+		t.uses = make(map[uint64]int)
+		t.histogram = make(map[int]uint64)
+	}
+	r := t.uses[leafidx] + 1
+	t.uses[leafidx] = r
+	if r > 1 {
+		if t.histogram[r-1]--; t.histogram[r-1] == 0 {
+			delete(t.histogram, r-1)
+		}
+	}
+	t.histogram[r]++
+	if r > t.maxUses {
+		t.maxUses = r
+	}
+	t.signatures++
+
+	var warnings []Warning
+	budget := t.budget()
+	if t.signatures == budget/2+1 {
+		warnings = append(warnings, Warning{Kind: BudgetApproaching, Signatures: t.signatures})
+	}
+	if t.signatures == budget+1 {
+		warnings = append(warnings, Warning{Kind: BudgetExceeded, Signatures: t.signatures})
+	}
+	if bits := InstanceBits(r); bits < t.minSecurityBits() {
+		warnings = append(warnings, Warning{Kind: InstanceOverused, Signatures: t.signatures, LeafIndex: leafidx, Uses: r, SecurityBits: bits})
+	}
+	return warnings
+}
+
+// Uses returns the number of times the HORST key pair with the leaf index
+// has been used.
+func (t *Tracker) Uses(leafidx uint64) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.uses[leafidx]
+}
+
+// Report returns a summary of the key's usage so far.
+func (t *Tracker) Report() *Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rep := &Report{
+		Signatures:        t.signatures,
+		Budget:            t.budget(),
+		Instances:         len(t.uses),
+		MaxUses:           t.maxUses,
+		Histogram:         make(map[int]uint64, len(t.histogram)),
+		WorstInstanceBits: InstanceBits(t.maxUses),
+		BoundBits:         BoundBits(t.signatures),
+	}
+	rep.BudgetUsed = float64(rep.Signatures) / float64(rep.Budget)
+
+	// The forgery targets a uniformly random HORST key pair, so the
+	// success probability is the average over all 2^h of them, where the
+	// unused ones contribute nothing.
+	terms := make([]float64, 0, len(t.histogram))
+	for r, n := range t.histogram {
+		rep.Histogram[r] = n
+		terms = append(terms, math.Log2(float64(n))-sphincs256.HypertreeHeight-InstanceBits(r))
+	}
+	rep.ObservedBits = -log2Sum(terms)
+	return rep
+}
+
+// InstanceBits returns the security level in bits of a single HORST key pair
+// that has been used r times, -log2(min(1, (rk/t)^k)).
+func InstanceBits(r int) float64 {
+	if r <= 0 {
+		return math.Inf(1)
+	}
+	bits := horst.K * (horst.LogT - math.Log2(float64(r)*horst.K))
+	if bits < 0 {
+		return 0
+	}
+	return bits
+}
+
+// BoundBits returns the security level in bits against a forgery after q
+// signatures, as per the bound from the SPHINCS paper.
+func BoundBits(q uint64) float64 {
+	const h = sphincs256.HypertreeHeight
+
+	// log2(1 - 2^-h), the probability of a signature not using a given
+	// HORST key pair.
+	log2Miss := math.Log1p(-math.Exp2(-h)) / math.Ln2
+	fq := float64(q)
+
+	var terms []float64
+	var log2Binom float64
+	for r := uint64(1); r <= q && r <= maxUses; r++ {
+		log2Binom += math.Log2(fq-float64(r-1)) - math.Log2(float64(r))
+		terms = append(terms, log2Binom+(fq-float64(r))*log2Miss-h*float64(r)-InstanceBits(int(r)))
+	}
+	return -log2Sum(terms)
+}
+
+// log2Sum returns log2(sum(2^x)) for the x in terms.
+func log2Sum(terms []float64) float64 {
+	max := math.Inf(-1)
+	for _, x := range terms {
+		if x > max {
+			max = x
+		}
+	}
+	if math.IsInf(max, -1) {
+		return max
+	}
+	var sum float64
+	for _, x := range terms {
+		sum += math.Exp2(x - max)
+	}
+	return max + math.Log2(sum)
+}
@@ -0,0 +1,129 @@
+// analysis_test.go - HORST key pair usage analysis tests
+
+package analysis
+
+import (
+	"crypto/rand"
+	"math"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+)
+
+func TestInstanceBits(t *testing.T) {
+	for _, v := range []struct {
+		r    int
+		bits float64
+	}{
+		{1, 352},
+		{2, 320},
+		{8, 256},
+		{128, 128},
+		{2048, 0},
+		{4096, 0},
+	} {
+		if bits := InstanceBits(v.r); math.Abs(bits-v.bits) > 1e-9 {
+			t.Errorf("InstanceBits(%d) = %v, expected %v", v.r, bits, v.bits)
+		}
+	}
+}
+
+func TestBoundBits(t *testing.T) {
+	// A single signature only reveals part of one HORST key pair.
+	if bits := BoundBits(1); math.Abs(bits-(sphincs256.HypertreeHeight+352)) > 1e-6 {
+		t.Fatalf("BoundBits(1) = %v", bits)
+	}
+
+	prev := math.Inf(1)
+	for _, log2q := range []uint{0, 10, 20, 30, 40, 50, 60, 64} {
+		q := uint64(math.MaxUint64)
+		if log2q < 64 {
+			q = 1 << log2q
+		}
+		bits := BoundBits(q)
+		if bits >= prev {
+			t.Fatalf("BoundBits(2^%d) = %v, not below %v", log2q, bits, prev)
+		}
+		prev = bits
+	}
+
+	// The signature budget should leave a comfortable margin.
+	if bits := BoundBits(DefaultBudget); bits < 256 {
+		t.Fatalf("BoundBits(DefaultBudget) = %v", bits)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := &Tracker{Budget: 8, MinSecurityBits: 300}
+
+	kinds := func(ws []Warning) []WarningKind {
+		var k []WarningKind
+		for _, w := range ws {
+			k = append(k, w.Kind)
+		}
+		return k
+	}
+
+	for i, v := range []struct {
+		leafidx uint64
+		kinds   []WarningKind
+	}{
+		{1, nil},
+		{2, nil},
+		{3, nil},
+		{1, nil},
+		{1, []WarningKind{BudgetApproaching}},
+		{1, []WarningKind{InstanceOverused}},
+		{4, nil},
+		{5, nil},
+		{6, []WarningKind{BudgetExceeded}},
+	} {
+		ws := tr.AddLeafIndex(v.leafidx)
+		if got := kinds(ws); len(got) != len(v.kinds) || (len(got) > 0 && got[0] != v.kinds[0]) {
+			t.Fatalf("[%d]: unexpected warnings: %v", i, ws)
+		}
+	}
+
+	if n := tr.Uses(1); n != 4 {
+		t.Fatalf("Uses(1) = %d", n)
+	}
+	rep := tr.Report()
+	if rep.Signatures != 9 || rep.Instances != 6 || rep.MaxUses != 4 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if rep.Histogram[1] != 5 || rep.Histogram[4] != 1 || len(rep.Histogram) != 2 {
+		t.Fatalf("unexpected histogram: %v", rep.Histogram)
+	}
+	if rep.WorstInstanceBits != InstanceBits(4) {
+		t.Fatalf("unexpected worst instance: %v", rep.WorstInstanceBits)
+	}
+
+	// The forgery probability is dominated by the most used key pair.
+	expected := sphincs256.HypertreeHeight + InstanceBits(4)
+	if rep.ObservedBits > expected || rep.ObservedBits < expected-1 {
+		t.Fatalf("unexpected observed security: %v", rep.ObservedBits)
+	}
+}
+
+func TestTrackerSignatures(t *testing.T) {
+	_, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	var tr Tracker
+	sig := sphincs256.Sign(sk, []byte("Ph'nglui mglw'nafh Cthulhu"))
+	for i := 0; i < 2; i++ {
+		if ws := tr.Add(sig); ws != nil {
+			t.Fatalf("unexpected warnings: %v", ws)
+		}
+	}
+
+	leafidx := sphincs256.LeafIndex(sig)
+	if leafidx>>sphincs256.HypertreeHeight != 0 {
+		t.Fatalf("leaf index out of range: %#x", leafidx)
+	}
+	if n := tr.Uses(leafidx); n != 2 {
+		t.Fatalf("Uses() = %d", n)
+	}
+}
@@ -0,0 +1,109 @@
+// bench_test.go - SPHINCS-256 benchmarks
+
+package sphincs256
+
+import (
+	"crypto/rand"
+	"strconv"
+	"testing"
+
+	"github.com/yawning/sphincs256/hash"
+)
+
+// benchSizes are the message sizes used by the size dependent benchmarks.
+var benchSizes = []int{32, 1024, 64 * 1024, 1024 * 1024}
+
+func BenchmarkGenerateKey(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := GenerateKey(rand.Reader); err != nil {
+			b.Fatalf("failed GenerateKey(): %s", err)
+		}
+	}
+}
+
+func benchKey(b *testing.B) (*[PublicKeySize]byte, *[PrivateKeySize]byte) {
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatalf("failed GenerateKey(): %s", err)
+	}
+	return pk, sk
+}
+
+func BenchmarkSignSize(b *testing.B) {
+	_, sk := benchKey(b)
+	scratch := NewScratch()
+	defer scratch.Destroy()
+
+	for _, n := range benchSizes {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var sig [SignatureSize]byte
+			msg := make([]byte, n)
+			SignTo(&sig, sk, msg, scratch)
+			b.SetBytes(int64(n))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				SignTo(&sig, sk, msg, scratch)
+			}
+		})
+	}
+}
+
+func BenchmarkVerifySize(b *testing.B) {
+	pk, sk := benchKey(b)
+
+	for _, n := range benchSizes {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			msg := make([]byte, n)
+			sig := Sign(sk, msg)
+			b.SetBytes(int64(n))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if !Verify(pk, msg, sig) {
+					b.Fatalf("failed Verify()")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkOpen(b *testing.B) {
+	pk, sk := benchKey(b)
+
+	for _, n := range benchSizes {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			msg := make([]byte, n)
+			sig := Sign(sk, msg)
+			sm := append(sig[:], msg...)
+			b.SetBytes(int64(n))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := Open(pk, sm); err != nil {
+					b.Fatalf("failed Open(): %s", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkComputeAuthpathWots(b *testing.B) {
+	_, sk := benchKey(b)
+
+	var root [hash.Size]byte
+	var authpath [subtreeHeight * hash.Size]byte
+	a := leafaddr{level: 0, subtree: 0, subleaf: 0}
+	ts := new(treeScratch)
+	defer ts.wots.Destroy()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ts.computeAuthpathWots(&root, authpath[:], &a, sk[:], sk[seedBytes:], subtreeHeight)
+	}
+}
@@ -0,0 +1,217 @@
+// budget.go - SPHINCS-256 private key signature budgets
+
+package sphincs256
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultSignatureLimit is the default signature limit for a
+// BudgetedPrivateKey, the 2^50 signatures assumed by the security analysis of
+// SPHINCS-256.
+const DefaultSignatureLimit = 1 << 50
+
+// CounterStore persists the monotonic signature counter of a
+// BudgetedPrivateKey.
+type CounterStore interface {
+	// Load returns the stored counter.  It must fail if the counter is
+	// missing, rather than return 0, since a lost counter would let
+	// signatures be made again.  Starting from 0 requires explicitly
+	// initializing the store (eg: CreateFileCounterStore).
+	Load() (uint64, error)
+
+	// Store persists the counter, and must not return until it is durable.
+	Store(n uint64) error
+}
+
+// BudgetExceededError is the error returned when signing with a
+// BudgetedPrivateKey would exceed its signature limit.
+type BudgetExceededError struct {
+	// Limit is the signature limit of the key.
+	Limit uint64
+
+	// Count is the number of signatures already made with the key.
+	Count uint64
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("sphincs256: signature limit reached (%d/%d)", e.Count, e.Limit)
+}
+
+// BudgetedPrivateKey is a SPHINCS-256 private key that refuses to sign more
+// than a configured number of messages.  The number of signatures made is
+// persisted via a CounterStore before each signature is computed, so crashes
+// can lose signatures from the budget, but never make them reusable.
+//
+// Only one BudgetedPrivateKey may use a given CounterStore (or private key)
+// at a time, including across processes.
+type BudgetedPrivateKey struct {
+	mu    sync.Mutex
+	key   *[PrivateKeySize]byte
+	store CounterStore
+	limit uint64
+	count uint64
+}
+
+// NewBudgetedPrivateKey wraps privateKey, which is not copied, so that at
+// most limit signatures are made with it, as counted by store.  If limit is 0,
+// DefaultSignatureLimit is used.
+func NewBudgetedPrivateKey(privateKey *[PrivateKeySize]byte, store CounterStore, limit uint64) (*BudgetedPrivateKey, error) {
+	if limit == 0 {
+		limit = DefaultSignatureLimit
+	}
+	count, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &BudgetedPrivateKey{key: privateKey, store: store, limit: limit, count: count}, nil
+}
+
+// Limit returns the signature limit.
+func (k *BudgetedPrivateKey) Limit() uint64 {
+	return k.limit
+}
+
+// Count returns the number of signatures made with the key, including those
+// that are in progress.
+func (k *BudgetedPrivateKey) Count() uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.count
+}
+
+// Remaining returns the number of signatures left in the budget.
+func (k *BudgetedPrivateKey) Remaining() uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.count >= k.limit {
+		return 0
+	}
+	return k.limit - k.count
+}
+
+// Sign signs the message and returns the signature, like Sign.  If the
+// signature limit has been reached, a *BudgetExceededError is returned.
+func (k *BudgetedPrivateKey) Sign(message []byte) (*[SignatureSize]byte, error) {
+	return k.SignWithOptions(message, nil)
+}
+
+// SignWithOptions signs the message and returns the signature as per opts,
+// like SignWithOptions.  If the signature limit has been reached, a
+// *BudgetExceededError is returned.
+func (k *BudgetedPrivateKey) SignWithOptions(message []byte, opts *SignOptions) (*[SignatureSize]byte, error) {
+	if err := k.reserve(); err != nil {
+		return nil, err
+	}
+	return sign(k.key, modePure, nil, message, nil, opts), nil
+}
+
+// reserve counts a signature against the budget, and persists the new count.
+func (k *BudgetedPrivateKey) reserve() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.count >= k.limit {
+		return &BudgetExceededError{Limit: k.limit, Count: k.count}
+	}
+	if err := k.store.Store(k.count + 1); err != nil {
+		return err
+	}
+	k.count++
+	return nil
+}
+
+// FileCounterStore is a CounterStore backed by a file, holding the counter in
+// decimal.  Updates are written to a temporary file in the same directory,
+// which is fsynced and atomically renamed over the old file, so the file
+// always holds either the old or the new counter.
+type FileCounterStore struct {
+	path string
+}
+
+// NewFileCounterStore returns a FileCounterStore backed by the existing file
+// at path, as created by CreateFileCounterStore.
+func NewFileCounterStore(path string) *FileCounterStore {
+	return &FileCounterStore{path: path}
+}
+
+// CreateFileCounterStore creates the file at path holding a counter of 0, and
+// returns a FileCounterStore backed by it.  It fails if the file already
+// exists, so that an existing counter is never reset.
+func CreateFileCounterStore(path string) (*FileCounterStore, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = writeAndSync(f, "0\n"); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	if err = syncDir(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return &FileCounterStore{path: path}, nil
+}
+
+// Load implements CounterStore.  It fails if the file does not exist.
+func (s *FileCounterStore) Load() (uint64, error) {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("sphincs256: malformed counter file %s: %v", s.path, err)
+	}
+	return n, nil
+}
+
+// Store implements CounterStore.
+func (s *FileCounterStore) Store(n uint64) error {
+	dir, name := filepath.Split(s.path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+name+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	if err = writeAndSync(f, strconv.FormatUint(n, 10)+"\n"); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// The rename is only durable once the directory is synced.
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func writeAndSync(f *os.File, s string) error {
+	if _, err := f.WriteString(s); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
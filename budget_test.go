@@ -0,0 +1,141 @@
+// budget_test.go - SPHINCS-256 signature budget tests
+
+package sphincs256
+
+import (
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type failingCounterStore struct {
+	n uint64
+}
+
+var errStoreFailed = errors.New("store failed")
+
+func (s *failingCounterStore) Load() (uint64, error) {
+	return s.n, nil
+}
+
+func (s *failingCounterStore) Store(n uint64) error {
+	return errStoreFailed
+}
+
+func TestBudgetedPrivateKey(t *testing.T) {
+	const msg = "The Call of Cthulhu"
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "counter")
+	if _, err = NewBudgetedPrivateKey(sk, NewFileCounterStore(path), 2); !os.IsNotExist(err) {
+		t.Fatalf("NewBudgetedPrivateKey() with a missing counter: %v", err)
+	}
+	store, err := CreateFileCounterStore(path)
+	if err != nil {
+		t.Fatalf("failed CreateFileCounterStore(): %s", err)
+	}
+	k, err := NewBudgetedPrivateKey(sk, store, 2)
+	if err != nil {
+		t.Fatalf("failed NewBudgetedPrivateKey(): %s", err)
+	}
+	if k.Count() != 0 || k.Remaining() != 2 {
+		t.Fatalf("unexpected initial count: %d/%d", k.Count(), k.Remaining())
+	}
+
+	sig, err := k.Sign([]byte(msg))
+	if err != nil {
+		t.Fatalf("failed Sign(): %s", err)
+	}
+	if !Verify(pk, []byte(msg), sig) {
+		t.Fatalf("failed Verify()")
+	}
+
+	// The count must survive reopening the key.
+	k, err = NewBudgetedPrivateKey(sk, NewFileCounterStore(path), 2)
+	if err != nil {
+		t.Fatalf("failed NewBudgetedPrivateKey(): %s", err)
+	}
+	if k.Count() != 1 {
+		t.Fatalf("count not persisted: %d", k.Count())
+	}
+	if _, err = k.SignWithOptions([]byte(msg), &SignOptions{Concurrency: 2}); err != nil {
+		t.Fatalf("failed SignWithOptions(): %s", err)
+	}
+
+	_, err = k.Sign([]byte(msg))
+	var budgetErr *BudgetExceededError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Sign() past the limit returned: %v", err)
+	}
+	if budgetErr.Limit != 2 || budgetErr.Count != 2 || k.Remaining() != 0 {
+		t.Fatalf("unexpected error: %+v", budgetErr)
+	}
+
+	// A failure to persist the counter must prevent signing.
+	k, err = NewBudgetedPrivateKey(sk, &failingCounterStore{}, 0)
+	if err != nil {
+		t.Fatalf("failed NewBudgetedPrivateKey(): %s", err)
+	}
+	if k.Limit() != DefaultSignatureLimit {
+		t.Fatalf("unexpected default limit: %d", k.Limit())
+	}
+	if _, err = k.Sign([]byte(msg)); err != errStoreFailed {
+		t.Fatalf("Sign() with a failing store returned: %v", err)
+	}
+	if k.Count() != 0 {
+		t.Fatalf("count advanced despite the store failing")
+	}
+}
+
+func TestFileCounterStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "counter")
+	if _, err := NewFileCounterStore(path).Load(); !os.IsNotExist(err) {
+		t.Fatalf("Load() of a missing file: %v", err)
+	}
+
+	s, err := CreateFileCounterStore(path)
+	if err != nil {
+		t.Fatalf("failed CreateFileCounterStore(): %s", err)
+	}
+	if n, err := s.Load(); err != nil || n != 0 {
+		t.Fatalf("Load() of a new file: %d, %v", n, err)
+	}
+	for _, n := range []uint64{1, 1 << 50, 1<<64 - 1} {
+		if err := s.Store(n); err != nil {
+			t.Fatalf("failed Store(%d): %s", n, err)
+		}
+		if m, err := s.Load(); err != nil || m != n {
+			t.Fatalf("Load() = %d, %v, expected %d", m, err, n)
+		}
+	}
+
+	// No temporary files should be left behind.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed ReadDir(): %s", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("unexpected directory contents: %v", entries)
+	}
+
+	if _, err = CreateFileCounterStore(path); !os.IsExist(err) {
+		t.Fatalf("CreateFileCounterStore() over an existing file: %v", err)
+	}
+	if n, err := s.Load(); err != nil || n != 1<<64-1 {
+		t.Fatalf("CreateFileCounterStore() reset the counter: %d, %v", n, err)
+	}
+
+	if err = os.WriteFile(path, []byte("bogus\n"), 0600); err != nil {
+		t.Fatalf("failed WriteFile(): %s", err)
+	}
+	if _, err = s.Load(); err == nil {
+		t.Fatalf("Load() accepted a malformed counter")
+	}
+}
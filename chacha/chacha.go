@@ -1,7 +1,8 @@
 // chacha.go - sphincs256/ref/permute.[h,c], prg.[h,c]
 
 // Package chacha implements the ChaCha12 stream cipher along with the
-// SPHINCS-256 permutation function.  It is only suitable for use as part of
+// SPHINCS-256 permutation function.  Other than the stream ciphers (see
+// NewCipher, NewXCipher and HChaCha12), it is only suitable for use as part of
 // the "sphincs256" package and should not be used for anything else.
 //
 // The implementation is based off the SUPERCOP "ref" portable C implementation
@@ -10,7 +11,10 @@ package chacha
 
 import (
 	"encoding/binary"
+	"runtime"
 	"strconv"
+
+	"github.com/yawning/sphincs256/utils"
 )
 
 const (
@@ -39,6 +43,24 @@ func (x *ctx) encryptBytes(m []byte, c []byte) {
 	if bytes <= 0 {
 		return
 	}
+	if haveBlocks4 {
+		// Generate the keystream 4 blocks at a time where that's faster.
+		var output4 [4 * BlockSize]byte
+		for bytes >= len(output4) {
+			blocks4(&output4, &x.input)
+			x.setCounter((uint64(x.input[13])<<32 | uint64(x.input[12])) + 4)
+			for i := 0; i < len(output4); i++ {
+				cc[i] = mm[i] ^ output4[i]
+			}
+			bytes -= len(output4)
+			cc = cc[len(output4):]
+			mm = mm[len(output4):]
+		}
+		utils.Zerobytes(output4[:])
+		if bytes == 0 {
+			return
+		}
+	}
 	for {
 		salsa20WordToByte(&output, &x.input)
 		x.input[12]++
@@ -50,6 +72,7 @@ func (x *ctx) encryptBytes(m []byte, c []byte) {
 			for i := 0; i < bytes; i++ {
 				cc[i] = mm[i] ^ output[i]
 			}
+			utils.Zerobytes(output[:])
 			return
 		}
 		for i := 0; i < len(output); i++ {
@@ -61,6 +84,14 @@ func (x *ctx) encryptBytes(m []byte, c []byte) {
 	}
 }
 
+// reset wipes the key material from the ctx.
+func (x *ctx) reset() {
+	for i := range x.input {
+		x.input[i] = 0
+	}
+	runtime.KeepAlive(x)
+}
+
 func (x *ctx) keystreamBytes(stream []byte) {
 	for i := 0; i < len(stream); i++ {
 		stream[i] = 0
@@ -68,9 +99,11 @@ func (x *ctx) keystreamBytes(stream []byte) {
 	x.encryptBytes(stream, stream)
 }
 
-func newCtx(k []byte) *ctx {
+// newCtx returns a ctx keyed with k.  It is returned by value so that it
+// can live on the caller's stack.
+func newCtx(k []byte) ctx {
 	var constants []byte
-	x := &ctx{}
+	x := ctx{}
 
 	x.input[4] = binary.LittleEndian.Uint32(k[0:])
 	x.input[5] = binary.LittleEndian.Uint32(k[4:])
@@ -100,6 +133,7 @@ func keystreamBytes(c, n, k []byte) {
 	ctx := newCtx(k)
 	ctx.ivSetup(n)
 	ctx.keystreamBytes(c)
+	ctx.reset()
 }
 
 // Prg is the SPHINCS-256 entropy expansion routine.  It fills 'r' with the
@@ -112,10 +146,37 @@ func Prg(r []byte, k []byte) {
 	keystreamBytes(r, prgNonce[:], k)
 }
 
-func doRounds(x *[16]uint32) {
+// PrgAt fills 'r' with the ChaCha12 keystream for key 'k', with an all zero
+// nonce, starting at byte 'offset' into the keystream.  The output is
+// identical to the corresponding part of Prg's output, but only the 64 byte
+// blocks that overlap with it are generated.
+func PrgAt(r []byte, k []byte, offset uint64) {
+	var prgNonce [8]byte
+	if len(k) != 32 {
+		panic("key length != seedBytes: " + strconv.Itoa(len(k)))
+	}
+	ctx := newCtx(k)
+	ctx.ivSetup(prgNonce[:])
+	ctx.setCounter(offset / BlockSize)
+
+	// Handle a leading partial block.
+	if skip := offset % BlockSize; skip != 0 && len(r) > 0 {
+		var block [BlockSize]byte
+		ctx.keystreamBytes(block[:])
+		n := copy(r, block[skip:])
+		utils.Zerobytes(block[:])
+		r = r[n:]
+	}
+	ctx.keystreamBytes(r)
+	ctx.reset()
+}
+
+// doRoundsGeneric is the portable round function.  The keystream and Permute
+// always use chachaRounds (via doRounds), only hChaCha takes other values.
+func doRoundsGeneric(x *[16]uint32, rounds int) {
 	x0, x1, x2, x3, x4, x5, x6, x7, x8, x9, x10, x11, x12, x13, x14, x15 := x[0], x[1], x[2], x[3], x[4], x[5], x[6], x[7], x[8], x[9], x[10], x[11], x[12], x[13], x[14], x[15]
 
-	for i := chachaRounds; i > 0; i -= 2 {
+	for i := rounds; i > 0; i -= 2 {
 		var xx uint32
 
 		// quarterround(x, 0, 4, 8, 12)
@@ -233,6 +294,23 @@ func doRounds(x *[16]uint32) {
 	x[0], x[1], x[2], x[3], x[4], x[5], x[6], x[7], x[8], x[9], x[10], x[11], x[12], x[13], x[14], x[15] = x0, x1, x2, x3, x4, x5, x6, x7, x8, x9, x10, x11, x12, x13, x14, x15
 }
 
+// blocks4Generic generates the 4 keystream blocks starting at the block
+// counter in input, without advancing the counter.
+func blocks4Generic(out *[4 * BlockSize]byte, input *[16]uint32) {
+	x := *input
+	for i := 0; i < 4; i++ {
+		salsa20WordToByte((*[BlockSize]byte)(out[i*BlockSize:]), &x)
+		x[12]++
+		if x[12] == 0 {
+			x[13]++
+		}
+	}
+	for i := range x {
+		x[i] = 0
+	}
+	runtime.KeepAlive(&x)
+}
+
 func salsa20WordToByte(output *[64]byte, input *[16]uint32) {
 	var x [16]uint32
 	copy(x[:], input[:])
@@ -240,5 +318,7 @@ func salsa20WordToByte(output *[64]byte, input *[16]uint32) {
 	for i := 0; i < len(x); i++ {
 		x[i] += input[i]
 		binary.LittleEndian.PutUint32(output[4*i:], x[i])
+		x[i] = 0
 	}
+	runtime.KeepAlive(&x)
 }
@@ -0,0 +1,24 @@
+// chacha_arm64.go - arm64 NEON ChaCha round function dispatch
+
+package chacha
+
+const haveBlocks4 = true
+
+//go:noescape
+func doRoundsNEON(x *[16]uint32, rounds int)
+
+//go:noescape
+func blocks4NEON(out *[4 * BlockSize]byte, input *[16]uint32, rounds int)
+
+func doRounds(x *[16]uint32) {
+	doRoundsNEON(x, chachaRounds)
+}
+
+func blocks4(out *[4 * BlockSize]byte, input *[16]uint32) {
+	if input[12] > ^uint32(0)-3 {
+		// The NEON code does not carry into the high word of the counter.
+		blocks4Generic(out, input)
+		return
+	}
+	blocks4NEON(out, input, chachaRounds)
+}
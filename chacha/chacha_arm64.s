@@ -0,0 +1,173 @@
+// chacha_arm64.s - arm64 NEON ChaCha round function
+
+#include "textflag.h"
+
+// QROUND applies the ChaCha quarter round to each 32 bit lane of a, b, c, d,
+// using t as scratch.  The 16 bit rotate is a halfword swap, the rest are a
+// shift followed by a shift-right-insert.
+#define QROUND(a, b, c, d, t) \
+	VADD	b.S4, a.S4, a.S4; \
+	VEOR	a.B16, d.B16, d.B16; \
+	VREV32	d.H8, d.H8; \
+	VADD	d.S4, c.S4, c.S4; \
+	VEOR	c.B16, b.B16, t.B16; \
+	VSHL	$12, t.S4, b.S4; \
+	VSRI	$20, t.S4, b.S4; \
+	VADD	b.S4, a.S4, a.S4; \
+	VEOR	a.B16, d.B16, t.B16; \
+	VSHL	$8, t.S4, d.S4; \
+	VSRI	$24, t.S4, d.S4; \
+	VADD	d.S4, c.S4, c.S4; \
+	VEOR	c.B16, b.B16, t.B16; \
+	VSHL	$7, t.S4, b.S4; \
+	VSRI	$25, t.S4, b.S4
+
+// ADDWORD adds the 32 bit word lane of in to every lane of v.
+#define ADDWORD(v, in, lane, t) \
+	VDUP	in.S[lane], t.S4; \
+	VADD	t.S4, v.S4, v.S4
+
+// func doRoundsNEON(x *[16]uint32, rounds int)
+//
+// One block, with a row of the state per register, rotating the rows
+// between the column and diagonal rounds.
+TEXT ·doRoundsNEON(SB), NOSPLIT, $0-16
+	MOVD	x+0(FP), R0
+	MOVD	rounds+8(FP), R1
+
+	VLD1	(R0), [V0.S4, V1.S4, V2.S4, V3.S4]
+
+	CMP	$0, R1
+	BLE	done1
+
+loop1:
+	QROUND(V0, V1, V2, V3, V4)
+	VEXT	$4, V1.B16, V1.B16, V1.B16
+	VEXT	$8, V2.B16, V2.B16, V2.B16
+	VEXT	$12, V3.B16, V3.B16, V3.B16
+	QROUND(V0, V1, V2, V3, V4)
+	VEXT	$12, V1.B16, V1.B16, V1.B16
+	VEXT	$8, V2.B16, V2.B16, V2.B16
+	VEXT	$4, V3.B16, V3.B16, V3.B16
+
+	SUBS	$2, R1, R1
+	BGT	loop1
+
+done1:
+	VST1	[V0.S4, V1.S4, V2.S4, V3.S4], (R0)
+	RET
+
+// func blocks4NEON(out *[4 * BlockSize]byte, input *[16]uint32, rounds int)
+//
+// Four consecutive blocks, with each register holding one word of the state
+// for all four blocks.  The caller is responsible for ensuring that the low
+// word of the block counter does not wrap.
+TEXT ·blocks4NEON(SB), NOSPLIT, $0-24
+	MOVD	out+0(FP), R0
+	MOVD	input+8(FP), R1
+	MOVD	rounds+16(FP), R2
+
+	MOVD	$·blocks4Incs(SB), R3
+	VLD1	(R3), [V30.S4]
+	VLD1	(R1), [V20.S4, V21.S4, V22.S4, V23.S4]
+
+	VDUP	V20.S[0], V0.S4
+	VDUP	V20.S[1], V1.S4
+	VDUP	V20.S[2], V2.S4
+	VDUP	V20.S[3], V3.S4
+	VDUP	V21.S[0], V4.S4
+	VDUP	V21.S[1], V5.S4
+	VDUP	V21.S[2], V6.S4
+	VDUP	V21.S[3], V7.S4
+	VDUP	V22.S[0], V8.S4
+	VDUP	V22.S[1], V9.S4
+	VDUP	V22.S[2], V10.S4
+	VDUP	V22.S[3], V11.S4
+	VDUP	V23.S[0], V12.S4
+	VDUP	V23.S[1], V13.S4
+	VDUP	V23.S[2], V14.S4
+	VDUP	V23.S[3], V15.S4
+	VADD	V30.S4, V12.S4, V12.S4
+
+	CMP	$0, R2
+	BLE	done4
+
+loop4:
+	QROUND(V0, V4, V8, V12, V16)
+	QROUND(V1, V5, V9, V13, V17)
+	QROUND(V2, V6, V10, V14, V18)
+	QROUND(V3, V7, V11, V15, V19)
+	QROUND(V0, V5, V10, V15, V16)
+	QROUND(V1, V6, V11, V12, V17)
+	QROUND(V2, V7, V8, V13, V18)
+	QROUND(V3, V4, V9, V14, V19)
+
+	SUBS	$2, R2, R2
+	BGT	loop4
+
+done4:
+	ADDWORD(V0, V20, 0, V16)
+	ADDWORD(V1, V20, 1, V17)
+	ADDWORD(V2, V20, 2, V18)
+	ADDWORD(V3, V20, 3, V19)
+	ADDWORD(V4, V21, 0, V16)
+	ADDWORD(V5, V21, 1, V17)
+	ADDWORD(V6, V21, 2, V18)
+	ADDWORD(V7, V21, 3, V19)
+	ADDWORD(V8, V22, 0, V16)
+	ADDWORD(V9, V22, 1, V17)
+	ADDWORD(V10, V22, 2, V18)
+	ADDWORD(V11, V22, 3, V19)
+	ADDWORD(V12, V23, 0, V16)
+	ADDWORD(V13, V23, 1, V17)
+	ADDWORD(V14, V23, 2, V18)
+	ADDWORD(V15, V23, 3, V19)
+	VADD	V30.S4, V12.S4, V12.S4
+
+	// Transpose from word-major to block-major order.
+	VZIP1	V1.S4, V0.S4, V16.S4
+	VZIP2	V1.S4, V0.S4, V17.S4
+	VZIP1	V3.S4, V2.S4, V18.S4
+	VZIP2	V3.S4, V2.S4, V19.S4
+	VZIP1	V5.S4, V4.S4, V20.S4
+	VZIP2	V5.S4, V4.S4, V21.S4
+	VZIP1	V7.S4, V6.S4, V22.S4
+	VZIP2	V7.S4, V6.S4, V23.S4
+	VZIP1	V9.S4, V8.S4, V24.S4
+	VZIP2	V9.S4, V8.S4, V25.S4
+	VZIP1	V11.S4, V10.S4, V26.S4
+	VZIP2	V11.S4, V10.S4, V27.S4
+	VZIP1	V13.S4, V12.S4, V28.S4
+	VZIP2	V13.S4, V12.S4, V29.S4
+	VZIP1	V15.S4, V14.S4, V30.S4
+	VZIP2	V15.S4, V14.S4, V31.S4
+
+	VZIP1	V18.D2, V16.D2, V0.D2
+	VZIP2	V18.D2, V16.D2, V4.D2
+	VZIP1	V19.D2, V17.D2, V8.D2
+	VZIP2	V19.D2, V17.D2, V12.D2
+	VZIP1	V22.D2, V20.D2, V1.D2
+	VZIP2	V22.D2, V20.D2, V5.D2
+	VZIP1	V23.D2, V21.D2, V9.D2
+	VZIP2	V23.D2, V21.D2, V13.D2
+	VZIP1	V26.D2, V24.D2, V2.D2
+	VZIP2	V26.D2, V24.D2, V6.D2
+	VZIP1	V27.D2, V25.D2, V10.D2
+	VZIP2	V27.D2, V25.D2, V14.D2
+	VZIP1	V30.D2, V28.D2, V3.D2
+	VZIP2	V30.D2, V28.D2, V7.D2
+	VZIP1	V31.D2, V29.D2, V11.D2
+	VZIP2	V31.D2, V29.D2, V15.D2
+
+	VST1.P	[V0.B16, V1.B16, V2.B16, V3.B16], 64(R0)
+	VST1.P	[V4.B16, V5.B16, V6.B16, V7.B16], 64(R0)
+	VST1.P	[V8.B16, V9.B16, V10.B16, V11.B16], 64(R0)
+	VST1.P	[V12.B16, V13.B16, V14.B16, V15.B16], 64(R0)
+	RET
+
+// Block counter increments for each lane.
+DATA	·blocks4Incs+0x00(SB)/4, $0
+DATA	·blocks4Incs+0x04(SB)/4, $1
+DATA	·blocks4Incs+0x08(SB)/4, $2
+DATA	·blocks4Incs+0x0c(SB)/4, $3
+GLOBL	·blocks4Incs(SB), NOPTR|RODATA, $16
@@ -0,0 +1,107 @@
+// chacha_arm64_test.go - arm64 NEON differential tests
+
+package chacha
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+)
+
+func randomState(t *testing.T) *[16]uint32 {
+	var b [64]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		t.Fatalf("failed rand.Read(): %s", err)
+	}
+	var x [16]uint32
+	for i := range x {
+		x[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return &x
+}
+
+func TestDoRoundsNEON(t *testing.T) {
+	for _, rounds := range []int{0, 2, 8, 12, 20} {
+		for i := 0; i < 100; i++ {
+			x := randomState(t)
+			expected := *x
+			doRoundsGeneric(&expected, rounds)
+			doRoundsNEON(x, rounds)
+			if *x != expected {
+				t.Fatalf("[%d]: doRoundsNEON() mismatch", rounds)
+			}
+		}
+	}
+}
+
+func TestBlocks4NEON(t *testing.T) {
+	for _, ctr := range []uint32{0, 1, 0x7fffffff, 0xfffffffb, 0xfffffffc, 0xffffffff} {
+		x := randomState(t)
+		x[12] = ctr
+		orig := *x
+
+		var expected, out [4 * BlockSize]byte
+		blocks4Generic(&expected, x)
+		blocks4(&out, x)
+		if out != expected {
+			t.Errorf("[%08x]: blocks4() mismatch", ctr)
+		}
+		if *x != orig {
+			t.Errorf("[%08x]: blocks4() modified the input", ctr)
+		}
+	}
+}
+
+func TestPermuteNEON(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		var buf [64]byte
+		rand.Read(buf[:])
+
+		var x [16]uint32
+		for j := range x {
+			x[j] = binary.LittleEndian.Uint32(buf[4*j:])
+		}
+		doRoundsGeneric(&x, chachaRounds)
+		var expected [64]byte
+		for j := range x {
+			binary.LittleEndian.PutUint32(expected[4*j:], x[j])
+		}
+
+		Permute(&buf)
+		if !bytes.Equal(buf[:], expected[:]) {
+			t.Fatalf("Permute() mismatch")
+		}
+	}
+}
+
+func TestPrgNEON(t *testing.T) {
+	var k [KeySize]byte
+	rand.Read(k[:])
+
+	// Prg uses the NEON 4 block function for whole groups of 4 blocks, and
+	// the NEON round function for the rest, so compare it against a
+	// keystream built with the portable round function only.
+	for _, n := range []int{1, BlockSize, 4 * BlockSize, 9*BlockSize + 7} {
+		out := make([]byte, n)
+		Prg(out, k[:])
+
+		x := newCtx(k[:])
+		var nonce [NonceSize]byte
+		x.ivSetup(nonce[:])
+		expected := make([]byte, 0, n+BlockSize)
+		for len(expected) < n {
+			y := x.input
+			doRoundsGeneric(&y, chachaRounds)
+			for i := range y {
+				var b [4]byte
+				binary.LittleEndian.PutUint32(b[:], y[i]+x.input[i])
+				expected = append(expected, b[:]...)
+			}
+			x.input[12]++
+		}
+		if !bytes.Equal(out, expected[:n]) {
+			t.Errorf("[%d]: Prg() does not match the portable keystream", n)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+// chacha_generic.go - Portable ChaCha round function dispatch
+
+// +build !arm64
+
+package chacha
+
+const haveBlocks4 = false
+
+func doRounds(x *[16]uint32) {
+	doRoundsGeneric(x, chachaRounds)
+}
+
+func blocks4(out *[4 * BlockSize]byte, input *[16]uint32) {
+	blocks4Generic(out, input)
+}
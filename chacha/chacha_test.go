@@ -4,7 +4,9 @@ package chacha
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/hex"
+	"strconv"
 	"testing"
 )
 
@@ -260,3 +262,49 @@ func TestKeystreamBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestPrgAt(t *testing.T) {
+	var key [32]byte
+	rand.Read(key[:])
+
+	expected := make([]byte, 8*BlockSize)
+	Prg(expected, key[:])
+
+	for _, offset := range []int{0, 1, 31, 32, 63, 64, 65, 200, 8*BlockSize - 1} {
+		for _, n := range []int{0, 1, 32, 63, 64, 65, 128} {
+			if offset+n > len(expected) {
+				continue
+			}
+			out := make([]byte, n)
+			PrgAt(out, key[:], uint64(offset))
+			if !bytes.Equal(out, expected[offset:offset+n]) {
+				t.Errorf("[%d, %d]: PrgAt() does not match Prg()", offset, n)
+			}
+		}
+	}
+}
+
+func BenchmarkPermute(b *testing.B) {
+	var buf [64]byte
+	b.SetBytes(int64(len(buf)))
+
+	for i := 0; i < b.N; i++ {
+		Permute(&buf)
+	}
+}
+
+func BenchmarkPrg(b *testing.B) {
+	// The sizes of a WOTS secret key, a chunk of HORST secret key elements,
+	// and a fully expanded HORST secret key.
+	for _, n := range []int{67 * 32, 64 * 32, 2 << 20} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			var key [32]byte
+			out := make([]byte, n)
+			b.SetBytes(int64(n))
+
+			for i := 0; i < b.N; i++ {
+				Prg(out, key[:])
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// +build !i386,!amd64
+// +build !386,!amd64,!arm64
 
 package chacha
 
@@ -0,0 +1,106 @@
+// stream.go - ChaCha12 cipher.Stream
+
+package chacha
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"runtime"
+)
+
+const (
+	// KeySize is the ChaCha12 key size in bytes.  128 bit keys are also
+	// supported, but not recommended.
+	KeySize = 32
+
+	// NonceSize is the ChaCha12 nonce size in bytes.
+	NonceSize = 8
+
+	// BlockSize is the ChaCha12 block size in bytes.
+	BlockSize = 64
+)
+
+// Cipher is a ChaCha12 stream cipher instance, with random access into the
+// keystream.
+type Cipher struct {
+	ctx ctx
+	buf [BlockSize]byte
+	off int
+}
+
+var _ cipher.Stream = (*Cipher)(nil)
+
+// NewCipher returns a new ChaCha12 Cipher, with the block counter set to 0.
+func NewCipher(key, nonce []byte) (*Cipher, error) {
+	if len(key) != KeySize && len(key) != KeySize/2 {
+		return nil, fmt.Errorf("chacha: invalid key size %d", len(key))
+	}
+	if len(nonce) != NonceSize {
+		return nil, fmt.Errorf("chacha: invalid nonce size %d", len(nonce))
+	}
+
+	c := &Cipher{ctx: newCtx(key), off: BlockSize}
+	c.ctx.ivSetup(nonce)
+	return c, nil
+}
+
+// XORKeyStream XORs each byte in src with a byte from the keystream and
+// writes the result to dst.  dst and src must overlap entirely or not at
+// all.  Generating more than 2^70 bytes of keystream is the caller's
+// responsibility.
+func (c *Cipher) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("chacha: output smaller than input")
+	}
+
+	// Drain the buffered partial block first.
+	if c.off < BlockSize {
+		n := xorBytes(dst, src, c.buf[c.off:])
+		c.off += n
+		dst, src = dst[n:], src[n:]
+	}
+
+	// Process whole blocks directly.
+	if n := len(src) &^ (BlockSize - 1); n > 0 {
+		c.ctx.encryptBytes(src[:n], dst[:n])
+		dst, src = dst[n:], src[n:]
+	}
+
+	// Buffer the keystream for the trailing partial block.
+	if len(src) > 0 {
+		c.ctx.keystreamBytes(c.buf[:])
+		c.off = xorBytes(dst, src, c.buf[:])
+	}
+}
+
+// SetCounter seeks to the start of the 64 byte keystream block ctr.
+func (c *Cipher) SetCounter(ctr uint64) {
+	c.ctx.setCounter(ctr)
+	c.off = BlockSize
+}
+
+// Reset wipes the key material and keystream from the Cipher.  The Cipher
+// must not be used after Reset is called.
+func (c *Cipher) Reset() {
+	c.ctx.reset()
+	for i := range c.buf {
+		c.buf[i] = 0
+	}
+	runtime.KeepAlive(c)
+}
+
+func (x *ctx) setCounter(ctr uint64) {
+	x.input[12] = uint32(ctr)
+	x.input[13] = uint32(ctr >> 32)
+}
+
+func xorBytes(dst, src, ks []byte) int {
+	n := len(src)
+	if len(ks) < n {
+		n = len(ks)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = src[i] ^ ks[i]
+	}
+	return n
+}
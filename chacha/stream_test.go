@@ -0,0 +1,103 @@
+// stream_test.go - ChaCha12 cipher.Stream tests
+
+package chacha
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+func TestCipher(t *testing.T) {
+	var key [KeySize]byte
+	var nonce [NonceSize]byte
+	rand.Read(key[:])
+	rand.Read(nonce[:])
+
+	expected := make([]byte, 16*BlockSize+17)
+	keystreamBytes(expected, nonce[:], key[:])
+
+	// Uneven chunk sizes must produce the same keystream.
+	for _, chunk := range []int{1, 7, 63, 64, 65, 129, len(expected)} {
+		c, err := NewCipher(key[:], nonce[:])
+		if err != nil {
+			t.Fatalf("failed NewCipher(): %s", err)
+		}
+		out := make([]byte, len(expected))
+		for off := 0; off < len(out); off += chunk {
+			end := off + chunk
+			if end > len(out) {
+				end = len(out)
+			}
+			c.XORKeyStream(out[off:end], out[off:end])
+		}
+		if !bytes.Equal(out, expected) {
+			t.Errorf("[%d]: keystream mismatch", chunk)
+		}
+	}
+
+	// Random access.
+	c, _ := NewCipher(key[:], nonce[:])
+	for _, ctr := range []uint64{5, 0, 16, 3} {
+		c.SetCounter(ctr)
+		out := make([]byte, len(expected)-int(ctr)*BlockSize)
+		c.XORKeyStream(out, out)
+		if !bytes.Equal(out, expected[ctr*BlockSize:]) {
+			t.Errorf("[%d]: keystream mismatch after SetCounter()", ctr)
+		}
+	}
+	c.Reset()
+}
+
+func TestCipherVector(t *testing.T) {
+	// Set 6, vector# 3 from TestKeystreamBytes.
+	key, _ := hex.DecodeString("0F62B5085BAE0154A7FA4DA0F34699EC3F92E5388BDE3184D72A7DD02376C91C")
+	nonce, _ := hex.DecodeString("288FF65DC42B92F9")
+	expected, _ := hex.DecodeString("49FD8FBF19EDCF3A198F5226AA480B97D9F16BA71A693C4ECB90C276094585DFA4FA259E1EC34DE444C92879BFE7F641EEAC480168DC8969A9C033151B1E9229")
+
+	c, err := NewCipher(key, nonce)
+	if err != nil {
+		t.Fatalf("failed NewCipher(): %s", err)
+	}
+	out := make([]byte, len(expected))
+	c.XORKeyStream(out, out)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("keystream mismatch")
+	}
+
+	if _, err = NewCipher(key[:31], nonce); err == nil {
+		t.Errorf("NewCipher() accepted an invalid key")
+	}
+	if _, err = NewCipher(key, key[:12]); err == nil {
+		t.Errorf("NewCipher() accepted an invalid nonce")
+	}
+}
+
+func TestCipherCounterCarry(t *testing.T) {
+	var key [KeySize]byte
+	var nonce [NonceSize]byte
+	rand.Read(key[:])
+	rand.Read(nonce[:])
+
+	// Generate blocks one at a time across the low counter word wrapping,
+	// and compare against the bulk keystream.
+	const start = 1<<32 - 6
+	x := newCtx(key[:])
+	x.ivSetup(nonce[:])
+	var expected []byte
+	for i := uint64(0); i < 12; i++ {
+		var block [BlockSize]byte
+		x.setCounter(start + i)
+		salsa20WordToByte(&block, &x.input)
+		expected = append(expected, block[:]...)
+	}
+
+	c, _ := NewCipher(key[:], nonce[:])
+	c.SetCounter(start)
+	out := make([]byte, len(expected))
+	c.XORKeyStream(out, out)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("keystream mismatch across counter wrap")
+	}
+}
@@ -0,0 +1,60 @@
+// xchacha.go - HChaCha12 and XChaCha12
+
+package chacha
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+)
+
+const (
+	// HNonceSize is the HChaCha12 nonce size in bytes.
+	HNonceSize = 16
+
+	// XNonceSize is the XChaCha12 nonce size in bytes.
+	XNonceSize = 24
+)
+
+// HChaCha12 derives a 32 byte subkey from a 32 byte key and a 16 byte nonce,
+// in the same manner as HChaCha20, but with 12 rounds.
+func HChaCha12(out *[KeySize]byte, key *[KeySize]byte, nonce *[HNonceSize]byte) {
+	hChaCha(out, key, nonce, chachaRounds)
+}
+
+// NewXCipher returns a new XChaCha12 Cipher, with the block counter set to
+// 0.  Only 256 bit keys are supported.
+func NewXCipher(key, nonce []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("chacha: invalid key size %d", len(key))
+	}
+	if len(nonce) != XNonceSize {
+		return nil, fmt.Errorf("chacha: invalid nonce size %d", len(nonce))
+	}
+
+	var subKey [KeySize]byte
+	hChaCha(&subKey, (*[KeySize]byte)(key), (*[HNonceSize]byte)(nonce[:HNonceSize]), chachaRounds)
+	c := &Cipher{ctx: newCtx(subKey[:]), off: BlockSize}
+	c.ctx.ivSetup(nonce[HNonceSize:])
+	for i := range subKey {
+		subKey[i] = 0
+	}
+	runtime.KeepAlive(&subKey)
+	return c, nil
+}
+
+// hChaCha is HChaCha with a variable number of rounds, so that it can be
+// checked against the HChaCha20 test vectors.  It is not performance
+// critical, so it always uses the portable round function.
+func hChaCha(out *[KeySize]byte, key *[KeySize]byte, nonce *[HNonceSize]byte, rounds int) {
+	x := newCtx(key[:])
+	for i := 0; i < 4; i++ {
+		x.input[12+i] = binary.LittleEndian.Uint32(nonce[4*i:])
+	}
+	doRoundsGeneric(&x.input, rounds)
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint32(out[4*i:], x.input[i])
+		binary.LittleEndian.PutUint32(out[16+4*i:], x.input[12+i])
+	}
+	x.reset()
+}
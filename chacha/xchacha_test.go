@@ -0,0 +1,98 @@
+// xchacha_test.go - HChaCha12 and XChaCha12 tests
+
+package chacha
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHChaCha20(t *testing.T) {
+	// draft-irtf-cfrg-xchacha-03, Section 2.2.1, exercising the HChaCha12
+	// code path with 20 rounds.
+	key, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	nonce, _ := hex.DecodeString("000000090000004a0000000031415927")
+	expected, _ := hex.DecodeString("82413b4227b27bfed30e42508a877d73a0f9e4d58a74a853c12ec41326d3ecdc")
+
+	var out [KeySize]byte
+	hChaCha(&out, (*[KeySize]byte)(key), (*[HNonceSize]byte)(nonce), 20)
+	if !bytes.Equal(out[:], expected) {
+		t.Errorf("HChaCha20: got %x", out[:])
+	}
+}
+
+func TestHChaCha12(t *testing.T) {
+	var key [KeySize]byte
+	var nonce [HNonceSize]byte
+	rand.Read(key[:])
+	rand.Read(nonce[:])
+
+	var out [KeySize]byte
+	HChaCha12(&out, &key, &nonce)
+
+	// HChaCha12 is the ChaCha12 block function without the final addition
+	// of the input, truncated to the words that aren't trivially known.
+	x := newCtx(key[:])
+	for i := 0; i < 4; i++ {
+		x.input[12+i] = binary.LittleEndian.Uint32(nonce[4*i:])
+	}
+	var block [BlockSize]byte
+	salsa20WordToByte(&block, &x.input)
+	for i, j := range []int{0, 1, 2, 3, 12, 13, 14, 15} {
+		w := binary.LittleEndian.Uint32(block[4*j:]) - x.input[j]
+		if w != binary.LittleEndian.Uint32(out[4*i:]) {
+			t.Errorf("[%d]: HChaCha12 does not match the block function", j)
+		}
+	}
+}
+
+func TestXChaCha12(t *testing.T) {
+	var key [KeySize]byte
+	var nonce [XNonceSize]byte
+	rand.Read(key[:])
+	rand.Read(nonce[:])
+
+	// XChaCha12 is ChaCha12 keyed with the HChaCha12 subkey, using the
+	// remainder of the nonce.
+	var subKey [KeySize]byte
+	HChaCha12(&subKey, &key, (*[HNonceSize]byte)(nonce[:HNonceSize]))
+	expected := make([]byte, 4*BlockSize+3)
+	keystreamBytes(expected, nonce[HNonceSize:], subKey[:])
+
+	c, err := NewXCipher(key[:], nonce[:])
+	if err != nil {
+		t.Fatalf("failed NewXCipher(): %s", err)
+	}
+	out := make([]byte, len(expected))
+	c.XORKeyStream(out, out)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("XChaCha12 keystream mismatch")
+	}
+
+	if _, err := NewXCipher(key[:KeySize/2], nonce[:]); err == nil {
+		t.Errorf("NewXCipher() accepted a 128 bit key")
+	}
+	if _, err := NewXCipher(key[:], nonce[:NonceSize]); err == nil {
+		t.Errorf("NewXCipher() accepted a short nonce")
+	}
+}
+
+func TestXChaCha12Vector(t *testing.T) {
+	// Keystream checked against an independent implementation.
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f5051525354555658")
+	expected, _ := hex.DecodeString("cb72ceaaf19c11a18b14e849a44c2dfb836fa21a04b1874725e683933dabbe2bce63c1d8a13e16b1963e4949b1fe2c540672e4eff413a715f881ae7d740da818660b2465e6c03c3d6283f2e42c40f1bcddede006aee605d04a5f3f27d7dbcb25")
+
+	c, err := NewXCipher(key, nonce)
+	if err != nil {
+		t.Fatalf("failed NewXCipher(): %s", err)
+	}
+	out := make([]byte, len(expected))
+	c.XORKeyStream(out, out)
+	if !bytes.Equal(out, expected) {
+		t.Errorf("XChaCha12: got %x", out)
+	}
+}
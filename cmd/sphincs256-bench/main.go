@@ -0,0 +1,220 @@
+// main.go - SPHINCS-256 benchmark tool
+
+// Command sphincs256-bench benchmarks SPHINCS-256 and its major components,
+// and prints the results as a table.
+//
+// Go has no portable way to read the cycle counter, so cycles/op is
+// estimated from the wall clock time and the CPU frequency passed with -ghz.
+// For meaningful numbers, disable frequency scaling and turbo boost, and run
+// on an otherwise idle machine.
+package main
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/yawning/sphincs256"
+	"github.com/yawning/sphincs256/chacha"
+	"github.com/yawning/sphincs256/hash"
+	"github.com/yawning/sphincs256/horst"
+	"github.com/yawning/sphincs256/wots"
+)
+
+type benchmark struct {
+	name string
+	fn   func(b *testing.B)
+}
+
+func main() {
+	ghz := flag.Float64("ghz", 0, "CPU frequency in GHz, used to estimate cycles/op")
+	benchTime := flag.String("benchtime", "1s", "run each benchmark for duration d, or Nx iterations")
+	run := flag.String("run", "", "only run benchmarks matching the regular expression")
+	components := flag.Bool("components", true, "also benchmark the components")
+	testing.Init()
+	flag.Parse()
+
+	if err := flag.Set("test.benchtime", *benchTime); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -benchtime: %v\n", err)
+		os.Exit(2)
+	}
+	re, err := regexp.Compile(*run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -run: %v\n", err)
+		os.Exit(2)
+	}
+
+	benchmarks := schemeBenchmarks()
+	if *components {
+		benchmarks = append(benchmarks, componentBenchmarks()...)
+	}
+
+	fmt.Printf("%s %s/%s, GOMAXPROCS=%d\n\n", runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.GOMAXPROCS(0))
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "benchmark\titerations\tns/op\tcycles/op\tMB/s\tB/op\tallocs/op\t")
+	for _, bm := range benchmarks {
+		if !re.MatchString(bm.name) {
+			continue
+		}
+		r := testing.Benchmark(bm.fn)
+		if r.N == 0 {
+			fmt.Fprintf(w, "%s\tFAILED\t\t\t\t\t\t\n", bm.name)
+			continue
+		}
+
+		cycles, mbps := "-", "-"
+		if *ghz > 0 {
+			cycles = fmt.Sprintf("%.0f", float64(r.T.Nanoseconds())/float64(r.N)**ghz)
+		}
+		if r.Bytes > 0 {
+			mbps = fmt.Sprintf("%.2f", float64(r.Bytes)*float64(r.N)/r.T.Seconds()/1e6)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%d\t%d\t\n", bm.name, r.N, r.NsPerOp(), cycles, mbps, r.AllocedBytesPerOp(), r.AllocsPerOp())
+	}
+	w.Flush()
+}
+
+func schemeBenchmarks() []benchmark {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	bms := []benchmark{
+		{"GenerateKey", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				sphincs256.GenerateKey(rand.Reader)
+			}
+		}},
+	}
+
+	for _, n := range []int{32, 1024, 64 * 1024} {
+		msg := make([]byte, n)
+		sig := sphincs256.Sign(sk, msg)
+		sm := append(sig[:], msg...)
+		bms = append(bms,
+			benchmark{fmt.Sprintf("Sign/%d", n), func(b *testing.B) {
+				var sig [sphincs256.SignatureSize]byte
+				scratch := sphincs256.NewScratch()
+				defer scratch.Destroy()
+				sphincs256.SignTo(&sig, sk, msg, scratch)
+				b.SetBytes(int64(len(msg)))
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					sphincs256.SignTo(&sig, sk, msg, scratch)
+				}
+			}},
+			benchmark{fmt.Sprintf("Verify/%d", n), func(b *testing.B) {
+				b.SetBytes(int64(len(msg)))
+				for i := 0; i < b.N; i++ {
+					if !sphincs256.Verify(pk, msg, sig) {
+						b.Fatalf("failed Verify()")
+					}
+				}
+			}},
+			benchmark{fmt.Sprintf("Open/%d", n), func(b *testing.B) {
+				b.SetBytes(int64(len(msg)))
+				for i := 0; i < b.N; i++ {
+					if _, err := sphincs256.Open(pk, sm); err != nil {
+						b.Fatalf("failed Open(): %v", err)
+					}
+				}
+			}},
+		)
+	}
+
+	opts := &sphincs256.SignOptions{Concurrency: runtime.GOMAXPROCS(0)}
+	bms = append(bms, benchmark{"SignConcurrent", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sphincs256.SignWithOptions(sk, nil, opts)
+		}
+	}})
+
+	return bms
+}
+
+func componentBenchmarks() []benchmark {
+	var seed [horst.SeedBytes]byte
+	var masks [2 * horst.LogT * hash.Size]byte
+	var mHash [64]byte
+	var msg [hash.Size]byte
+	rand.Read(seed[:])
+	rand.Read(masks[:])
+	rand.Read(mHash[:])
+	rand.Read(msg[:])
+
+	var horstPk [hash.Size]byte
+	horstSig := make([]byte, horst.SigBytes)
+	horst.Sign(horstSig, &horstPk, nil, &seed, masks[:], mHash[:], 1, nil)
+
+	var wotsSig [wots.SigBytes]byte
+	wots.Sign(wotsSig[:], &msg, &seed, masks[:], nil)
+
+	bms := []benchmark{
+		{"horst.Sign", func(b *testing.B) {
+			var pk [hash.Size]byte
+			sig := make([]byte, horst.SigBytes)
+			scratch := new(horst.Scratch)
+			defer scratch.Destroy()
+			horst.Sign(sig, &pk, nil, &seed, masks[:], mHash[:], 1, scratch)
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				horst.Sign(sig, &pk, nil, &seed, masks[:], mHash[:], 1, scratch)
+			}
+		}},
+		{"horst.Verify", func(b *testing.B) {
+			var pk [hash.Size]byte
+			for i := 0; i < b.N; i++ {
+				horst.Verify(pk[:], horstSig, nil, masks[:], mHash[:])
+			}
+		}},
+		{"wots.Pkgen", func(b *testing.B) {
+			var pk [wots.L * hash.Size]byte
+			scratch := new(wots.Scratch)
+			defer scratch.Destroy()
+			for i := 0; i < b.N; i++ {
+				wots.Pkgen(pk[:], seed[:], masks[:], scratch)
+			}
+		}},
+		{"wots.Sign", func(b *testing.B) {
+			var sig [wots.SigBytes]byte
+			scratch := new(wots.Scratch)
+			defer scratch.Destroy()
+			for i := 0; i < b.N; i++ {
+				wots.Sign(sig[:], &msg, &seed, masks[:], scratch)
+			}
+		}},
+		{"wots.Verify", func(b *testing.B) {
+			var pk [wots.L * hash.Size]byte
+			for i := 0; i < b.N; i++ {
+				wots.Verify(&pk, wotsSig[:], &msg, masks[:])
+			}
+		}},
+		{"chacha.Permute", func(b *testing.B) {
+			var buf [64]byte
+			b.SetBytes(int64(len(buf)))
+			for i := 0; i < b.N; i++ {
+				chacha.Permute(&buf)
+			}
+		}},
+	}
+
+	for _, n := range []int{wots.L * hash.Size, horst.T * horst.SkBytes} {
+		out := make([]byte, n)
+		bms = append(bms, benchmark{fmt.Sprintf("chacha.Prg/%d", n), func(b *testing.B) {
+			b.SetBytes(int64(len(out)))
+			for i := 0; i < b.N; i++ {
+				chacha.Prg(out, seed[:])
+			}
+		}})
+	}
+
+	return bms
+}
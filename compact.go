@@ -0,0 +1,99 @@
+// compact.go - SPHINCS-256 compact public keys
+
+package sphincs256
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/yawning/sphincs256/chacha"
+	"github.com/yawning/sphincs256/hash"
+)
+
+const (
+	// CompactPublicKeySize is the length of a SPHINCS-256 compact public key
+	// in bytes.
+	CompactPublicKeySize = publicSeedBytes + hash.Size
+
+	publicSeedBytes = 32
+
+	publicSeedContext = "SPHINCS-256 compact key public seed\x00"
+)
+
+// ErrNotCompact is the error returned by CompactPublicKeyFromPrivateKey when
+// the private key was not generated in compact mode.
+var ErrNotCompact = errors.New("sphincs256: private key is not a compact key")
+
+// KeyOptions are the optional parameters for GenerateKeyWithOptions.
+type KeyOptions struct {
+	// Compact derives the bitmasks from a public seed, instead of sampling
+	// them directly, so that the public key can be represented as just the
+	// public seed and the hypertree root (see CompactPublicKeyFromPrivateKey
+	// and ExpandPublicKey).
+	//
+	// The public seed is derived from the secret seed, so the compact
+	// public key can always be recovered from the private key.  The private
+	// key format is unchanged, and it can be used with Sign as is.
+	Compact bool
+}
+
+// GenerateKeyWithOptions generates a public/private key pair using
+// randomness from rand, as per opts.  If opts is nil, it is equivalent to
+// GenerateKey.
+func GenerateKeyWithOptions(rand io.Reader, opts *KeyOptions) (publicKey *[PublicKeySize]byte, privateKey *[PrivateKeySize]byte, err error) {
+	if opts == nil || !opts.Compact {
+		return GenerateKey(rand)
+	}
+
+	// Sample the secret seed and the secret random seed, and derive the
+	// bitmasks.
+	privateKey = new([PrivateKeySize]byte)
+	if _, err = io.ReadFull(rand, privateKey[:seedBytes]); err != nil {
+		return nil, nil, err
+	}
+	if _, err = io.ReadFull(rand, privateKey[PrivateKeySize-skRandSeedBytes:]); err != nil {
+		return nil, nil, err
+	}
+	publicSeed := derivePublicSeed(privateKey)
+	chacha.Prg(privateKey[seedBytes:seedBytes+nMasks*hash.Size], publicSeed[:])
+
+	return PublicKeyFromPrivateKey(privateKey), privateKey, nil
+}
+
+// CompactPublicKeyFromPrivateKey returns the compact public key corresponding
+// to privateKey, which must have been generated in compact mode.
+func CompactPublicKeyFromPrivateKey(privateKey *[PrivateKeySize]byte) (*[CompactPublicKeySize]byte, error) {
+	publicSeed := derivePublicSeed(privateKey)
+	var masks [nMasks * hash.Size]byte
+	chacha.Prg(masks[:], publicSeed[:])
+	if !bytes.Equal(masks[:], privateKey[seedBytes:seedBytes+nMasks*hash.Size]) {
+		return nil, ErrNotCompact
+	}
+
+	compactPublicKey := new([CompactPublicKeySize]byte)
+	copy(compactPublicKey[:publicSeedBytes], publicSeed[:])
+	publicKey := PublicKeyFromPrivateKey(privateKey)
+	copy(compactPublicKey[publicSeedBytes:], publicKey[nMasks*hash.Size:])
+	return compactPublicKey, nil
+}
+
+// ExpandPublicKey expands a compact public key into a regular public key.
+func ExpandPublicKey(compactPublicKey *[CompactPublicKeySize]byte) *[PublicKeySize]byte {
+	publicKey := new([PublicKeySize]byte)
+	chacha.Prg(publicKey[:nMasks*hash.Size], compactPublicKey[:publicSeedBytes])
+	copy(publicKey[nMasks*hash.Size:], compactPublicKey[publicSeedBytes:])
+	return publicKey
+}
+
+// derivePublicSeed derives the public seed from the secret seed, with a one
+// way function, so that publishing it reveals nothing about the secret seed.
+func derivePublicSeed(privateKey *[PrivateKeySize]byte) *[publicSeedBytes]byte {
+	var buf [len(publicSeedContext) + seedBytes]byte
+	copy(buf[:], publicSeedContext)
+	copy(buf[len(publicSeedContext):], privateKey[:seedBytes])
+
+	publicSeed := new([publicSeedBytes]byte)
+	hash.Varlen(publicSeed[:], buf[:])
+	return publicSeed
+}
@@ -0,0 +1,48 @@
+// compact_test.go - SPHINCS-256 compact public key tests
+
+package sphincs256
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestCompactKey(t *testing.T) {
+	const msg = "It was a mountain walking or stumbling."
+
+	pk, sk, err := GenerateKeyWithOptions(rand.Reader, &KeyOptions{Compact: true})
+	if err != nil {
+		t.Fatalf("failed GenerateKeyWithOptions(): %s", err)
+	}
+	if *pk != *PublicKeyFromPrivateKey(sk) {
+		t.Fatalf("PublicKeyFromPrivateKey() does not match GenerateKeyWithOptions()")
+	}
+
+	cpk, err := CompactPublicKeyFromPrivateKey(sk)
+	if err != nil {
+		t.Fatalf("failed CompactPublicKeyFromPrivateKey(): %s", err)
+	}
+	if *ExpandPublicKey(cpk) != *pk {
+		t.Fatalf("expanded public key does not match private key")
+	}
+
+	sig := Sign(sk, []byte(msg))
+	if !Verify(ExpandPublicKey(cpk), []byte(msg), sig) {
+		t.Fatalf("failed Verify() with expanded public key")
+	}
+
+	// Altering the public seed changes the masks, which breaks verification.
+	cpk[0] ^= 1
+	if Verify(ExpandPublicKey(cpk), []byte(msg), sig) {
+		t.Fatalf("Verify() succeeded with the wrong public seed")
+	}
+
+	// Regular keys have no compact form.
+	_, sk, err = GenerateKeyWithOptions(rand.Reader, nil)
+	if err != nil {
+		t.Fatalf("failed GenerateKeyWithOptions(): %s", err)
+	}
+	if _, err = CompactPublicKeyFromPrivateKey(sk); err != ErrNotCompact {
+		t.Fatalf("CompactPublicKeyFromPrivateKey() of a regular key: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// composite.go - Hybrid SPHINCS-256 + Ed25519 signatures
+
+// Package composite implements hybrid signatures that pair SPHINCS-256 with
+// Ed25519, such that a signature is only valid if both component signatures
+// are valid.  The construction remains secure as long as either primitive
+// does.
+//
+// Both component algorithms sign the message prefixed with a domain
+// separation string and a hash of the composite public key, so that a
+// component signature stripped from a composite signature is not a valid
+// stand-alone signature over the message, nor part of a valid composite
+// signature under a public key with a different other component.
+package composite
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"io"
+
+	"github.com/yawning/sphincs256"
+	"github.com/yawning/sphincs256/hash"
+)
+
+const (
+	// PublicKeySize is the length of a composite public key in bytes.
+	PublicKeySize = sphincs256.PublicKeySize + ed25519.PublicKeySize
+
+	// PrivateKeySize is the length of a composite private key in bytes.
+	PrivateKeySize = sphincs256.PrivateKeySize + ed25519.PrivateKeySize
+
+	// SignatureSize is the length of a composite signature in bytes.
+	SignatureSize = sphincs256.SignatureSize + ed25519.SignatureSize
+
+	domainSeparator = "SPHINCS256-Ed25519-Composite-v1\x00"
+)
+
+// PublicKey is a composite public key.
+type PublicKey struct {
+	SPHINCS *[sphincs256.PublicKeySize]byte
+	Ed25519 ed25519.PublicKey
+}
+
+// Bytes returns the serialized public key.  The encoding is the SPHINCS-256
+// public key followed by the Ed25519 public key.
+func (k *PublicKey) Bytes() *[PublicKeySize]byte {
+	var b [PublicKeySize]byte
+	copy(b[:sphincs256.PublicKeySize], k.SPHINCS[:])
+	copy(b[sphincs256.PublicKeySize:], k.Ed25519)
+	return &b
+}
+
+// PrivateKey is a composite private key.
+type PrivateKey struct {
+	SPHINCS *[sphincs256.PrivateKeySize]byte
+	Ed25519 ed25519.PrivateKey
+
+	publicKey *PublicKey
+}
+
+// Public returns the PublicKey corresponding to the private key.
+func (k *PrivateKey) Public() *PublicKey {
+	return k.publicKey
+}
+
+// Bytes returns the serialized private key.  The encoding is the SPHINCS-256
+// private key followed by the Ed25519 private key.
+func (k *PrivateKey) Bytes() *[PrivateKeySize]byte {
+	var b [PrivateKeySize]byte
+	copy(b[:sphincs256.PrivateKeySize], k.SPHINCS[:])
+	copy(b[sphincs256.PrivateKeySize:], k.Ed25519)
+	return &b
+}
+
+// GenerateKey generates a public/private key pair using randomness from rand.
+func GenerateKey(rand io.Reader) (publicKey *PublicKey, privateKey *PrivateKey, err error) {
+	sPk, sSk, err := sphincs256.GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+	ePk, eSk, err := ed25519.GenerateKey(rand)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey = &PublicKey{SPHINCS: sPk, Ed25519: ePk}
+	privateKey = &PrivateKey{SPHINCS: sSk, Ed25519: eSk, publicKey: publicKey}
+	return
+}
+
+// NewPublicKey deserializes a composite public key.
+func NewPublicKey(b []byte) (*PublicKey, error) {
+	if len(b) != PublicKeySize {
+		return nil, fmt.Errorf("composite: invalid public key length: %d", len(b))
+	}
+
+	k := &PublicKey{
+		SPHINCS: new([sphincs256.PublicKeySize]byte),
+		Ed25519: make(ed25519.PublicKey, ed25519.PublicKeySize),
+	}
+	copy(k.SPHINCS[:], b[:sphincs256.PublicKeySize])
+	copy(k.Ed25519, b[sphincs256.PublicKeySize:])
+	return k, nil
+}
+
+// NewPrivateKey deserializes a composite private key.
+func NewPrivateKey(b []byte) (*PrivateKey, error) {
+	if len(b) != PrivateKeySize {
+		return nil, fmt.Errorf("composite: invalid private key length: %d", len(b))
+	}
+
+	k := &PrivateKey{
+		SPHINCS: new([sphincs256.PrivateKeySize]byte),
+		Ed25519: make(ed25519.PrivateKey, ed25519.PrivateKeySize),
+	}
+	copy(k.SPHINCS[:], b[:sphincs256.PrivateKeySize])
+	copy(k.Ed25519, b[sphincs256.PrivateKeySize:])
+
+	// The SPHINCS-256 public key is not stored in the private key, so it
+	// needs to be regenerated.
+	k.publicKey = k.derivePublic()
+	return k, nil
+}
+
+func (k *PrivateKey) derivePublic() *PublicKey {
+	return &PublicKey{
+		SPHINCS: sphincs256.PublicKeyFromPrivateKey(k.SPHINCS),
+		Ed25519: k.Ed25519.Public().(ed25519.PublicKey),
+	}
+}
+
+// Sign signs the message with privateKey and returns the signature.  The
+// encoding is the SPHINCS-256 signature followed by the Ed25519 signature.
+func Sign(privateKey *PrivateKey, message []byte) *[SignatureSize]byte {
+	var sig [SignatureSize]byte
+
+	publicKey := privateKey.publicKey
+	if publicKey == nil {
+		publicKey = privateKey.derivePublic()
+	}
+	m := prefixMessage(publicKey, message)
+	sSig := sphincs256.Sign(privateKey.SPHINCS, m)
+	copy(sig[:sphincs256.SignatureSize], sSig[:])
+	copy(sig[sphincs256.SignatureSize:], ed25519.Sign(privateKey.Ed25519, m))
+	return &sig
+}
+
+// Verify takes a public key, message and signature and returns true if both
+// of the component signatures are valid.  Malformed public keys are never
+// valid.
+func Verify(publicKey *PublicKey, message []byte, signature *[SignatureSize]byte) bool {
+	if publicKey.SPHINCS == nil || len(publicKey.Ed25519) != ed25519.PublicKeySize {
+		return false
+	}
+
+	var sSig [sphincs256.SignatureSize]byte
+	copy(sSig[:], signature[:sphincs256.SignatureSize])
+
+	m := prefixMessage(publicKey, message)
+	sOk := sphincs256.Verify(publicKey.SPHINCS, m, &sSig)
+	eOk := ed25519.Verify(publicKey.Ed25519, m, signature[sphincs256.SignatureSize:])
+	return sOk && eOk
+}
+
+// prefixMessage returns the message signed by both component algorithms,
+// the domain separation string, the hash of the serialized public key, and
+// the message.
+func prefixMessage(publicKey *PublicKey, message []byte) []byte {
+	var pkHash [hash.Size]byte
+	hash.Varlen(pkHash[:], publicKey.Bytes()[:])
+
+	m := make([]byte, 0, len(domainSeparator)+len(pkHash)+len(message))
+	m = append(m, domainSeparator...)
+	m = append(m, pkHash[:]...)
+	return append(m, message...)
+}
@@ -0,0 +1,112 @@
+// composite_test.go - Hybrid SPHINCS-256 + Ed25519 signature tests
+
+package composite
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+)
+
+func TestSignVerify(t *testing.T) {
+	const msg = "The most merciful thing in the world is the inability of the human mind to correlate all its contents."
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	sig := Sign(sk, []byte(msg))
+	if !Verify(pk, []byte(msg), sig) {
+		t.Fatalf("failed Verify()")
+	}
+	if Verify(pk, []byte(msg[1:]), sig) {
+		t.Errorf("Verify() accepted the wrong message")
+	}
+
+	// Both components must be valid.
+	for _, off := range []int{0, sphincs256.SignatureSize} {
+		sig[off] ^= 0x01
+		if Verify(pk, []byte(msg), sig) {
+			t.Errorf("Verify() accepted a signature with a corrupted component at %d", off)
+		}
+		sig[off] ^= 0x01
+	}
+
+	// The components must not verify as stand-alone signatures.
+	var sSig [sphincs256.SignatureSize]byte
+	copy(sSig[:], sig[:])
+	if sphincs256.Verify(pk.SPHINCS, []byte(msg), &sSig) {
+		t.Errorf("SPHINCS-256 component verifies as a stand-alone signature")
+	}
+	if ed25519.Verify(pk.Ed25519, []byte(msg), sig[sphincs256.SignatureSize:]) {
+		t.Errorf("Ed25519 component verifies as a stand-alone signature")
+	}
+
+	// The components must not verify in a composite signature under a
+	// public key with a different other component.
+	pk2, sk2, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	mixed := &PublicKey{SPHINCS: pk.SPHINCS, Ed25519: pk2.Ed25519}
+	forged := *sig
+	copy(forged[sphincs256.SignatureSize:], ed25519.Sign(sk2.Ed25519, prefixMessage(mixed, []byte(msg))))
+	if Verify(mixed, []byte(msg), &forged) {
+		t.Errorf("SPHINCS-256 component verifies with a different Ed25519 key")
+	}
+
+	// Malformed public keys must not verify, or panic.
+	if Verify(&PublicKey{SPHINCS: pk.SPHINCS, Ed25519: pk.Ed25519[1:]}, []byte(msg), sig) {
+		t.Errorf("Verify() accepted a truncated Ed25519 key")
+	}
+	if Verify(&PublicKey{Ed25519: pk.Ed25519}, []byte(msg), sig) {
+		t.Errorf("Verify() accepted a missing SPHINCS-256 key")
+	}
+
+	// Keys without a cached public key sign the same.
+	bare := &PrivateKey{SPHINCS: sk.SPHINCS, Ed25519: sk.Ed25519}
+	if !Verify(pk, []byte(msg), Sign(bare, []byte(msg))) {
+		t.Errorf("failed Verify() of a signature by a bare private key")
+	}
+}
+
+func TestSerialization(t *testing.T) {
+	const msg = "Searchers after horror haunt strange, far places."
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	pk2, err := NewPublicKey(pk.Bytes()[:])
+	if err != nil {
+		t.Fatalf("failed NewPublicKey(): %s", err)
+	}
+	if !bytes.Equal(pk.Bytes()[:], pk2.Bytes()[:]) {
+		t.Fatalf("public key round trip mismatch")
+	}
+
+	sk2, err := NewPrivateKey(sk.Bytes()[:])
+	if err != nil {
+		t.Fatalf("failed NewPrivateKey(): %s", err)
+	}
+	if !bytes.Equal(pk.Bytes()[:], sk2.Public().Bytes()[:]) {
+		t.Fatalf("deserialized private key has the wrong public key")
+	}
+
+	sig := Sign(sk2, []byte(msg))
+	if !Verify(pk2, []byte(msg), sig) {
+		t.Fatalf("failed Verify() with deserialized keys")
+	}
+
+	if _, err = NewPublicKey(pk.Bytes()[1:]); err == nil {
+		t.Errorf("NewPublicKey() accepted a truncated key")
+	}
+	if _, err = NewPrivateKey(sk.Bytes()[1:]); err == nil {
+		t.Errorf("NewPrivateKey() accepted a truncated key")
+	}
+}
@@ -0,0 +1,112 @@
+// context_test.go - SPHINCS-256 context string tests
+
+package sphincs256
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestSignWithContext(t *testing.T) {
+	const msg = "The Dunwich Horror"
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	contexts := [][]byte{
+		nil,
+		[]byte("protocol A"),
+		[]byte("protocol B"),
+		[]byte("protocol A\x00"),
+		bytes.Repeat([]byte{'x'}, MaxContextSize),
+	}
+	sigs := make([]*[SignatureSize]byte, len(contexts))
+	for i, ctx := range contexts {
+		if sigs[i], err = SignWithContext(sk, ctx, []byte(msg)); err != nil {
+			t.Fatalf("[%d]: failed SignWithContext(): %s", i, err)
+		}
+	}
+
+	for i, sig := range sigs {
+		for j, ctx := range contexts {
+			if ok := VerifyWithContext(pk, ctx, []byte(msg), sig); ok != (i == j) {
+				t.Fatalf("VerifyWithContext() with signature %d and context %d: %v", i, j, ok)
+			}
+		}
+		if Verify(pk, []byte(msg), sig) {
+			t.Fatalf("[%d]: context signature verified with Verify()", i)
+		}
+	}
+
+	// Moving bytes between the context and the message must not verify.
+	sig, err := SignWithContext(sk, []byte("ab"), []byte("c"))
+	if err != nil {
+		t.Fatalf("failed SignWithContext(): %s", err)
+	}
+	if VerifyWithContext(pk, []byte("a"), []byte("bc"), sig) {
+		t.Fatalf("signature verified with a shifted context")
+	}
+
+	if VerifyWithContext(pk, nil, []byte(msg), Sign(sk, []byte(msg))) {
+		t.Fatalf("pure signature verified with VerifyWithContext()")
+	}
+
+	// Neither must signatures in the other modes over the exact bytes that
+	// a context signature covers, or vice versa.
+	dom, _ := contextDom(contexts[1])
+	encoded := append(dom, msg...)
+	if VerifyWithContext(pk, contexts[1], []byte(msg), Sign(sk, encoded)) {
+		t.Fatalf("pure signature over the encoded context verified with VerifyWithContext()")
+	}
+	if Verify(pk, encoded, sigs[1]) {
+		t.Fatalf("context signature verified as pure over the encoded context")
+	}
+	// The OID's DER tag (6) doubles as the length prefix of a context.
+	digest := sha256.Sum256([]byte(msg))
+	oid := prehashOIDs[crypto.SHA256]
+	ctx, m := oid[1:1+oid[0]], append(append([]byte{}, oid[1+oid[0]:]...), digest[:]...)
+	if sig, err = SignPrehashed(sk, crypto.SHA256, digest[:]); err != nil {
+		t.Fatalf("failed SignPrehashed(): %s", err)
+	}
+	if VerifyWithContext(pk, ctx, m, sig) {
+		t.Fatalf("pre-hashed signature verified with VerifyWithContext()")
+	}
+	ctxSig, err := SignWithContext(sk, ctx, m)
+	if err != nil {
+		t.Fatalf("failed SignWithContext(): %s", err)
+	}
+	if VerifyPrehashed(pk, crypto.SHA256, digest[:], ctxSig) {
+		t.Fatalf("context signature verified with VerifyPrehashed()")
+	}
+
+	// Nor must tweaking the attacker controlled R move a signature between
+	// modes.
+	pureSig := Sign(sk, encoded)
+	for _, delta := range []byte{modePrehash, modeContext, modePrehash ^ modeContext} {
+		if VerifyWithContext(pk, contexts[1], []byte(msg), tweakR(pureSig, delta)) {
+			t.Fatalf("pure signature with R ^ %d verified with VerifyWithContext()", delta)
+		}
+		if Verify(pk, encoded, tweakR(sigs[1], delta)) {
+			t.Fatalf("context signature with R ^ %d verified as pure", delta)
+		}
+		if VerifyWithContext(pk, ctx, m, tweakR(sig, delta)) {
+			t.Fatalf("pre-hashed signature with R ^ %d verified with VerifyWithContext()", delta)
+		}
+		if VerifyPrehashed(pk, crypto.SHA256, digest[:], tweakR(ctxSig, delta)) {
+			t.Fatalf("context signature with R ^ %d verified with VerifyPrehashed()", delta)
+		}
+	}
+
+	tooLong := make([]byte, MaxContextSize+1)
+	if _, err = SignWithContext(sk, tooLong, []byte(msg)); err != ErrContextTooLong {
+		t.Fatalf("SignWithContext() with a long context returned: %v", err)
+	}
+	if VerifyWithContext(pk, tooLong, []byte(msg), sigs[0]) {
+		t.Fatalf("VerifyWithContext() accepted a long context")
+	}
+}
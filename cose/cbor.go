@@ -0,0 +1,303 @@
+// cbor.go - Minimal CBOR (RFC 8949) encoder/decoder
+
+package cose
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// This is just enough CBOR to handle COSE structures.  Floating point values
+// and indefinite length items are not supported.  Maps are always encoded
+// with deterministically sorted keys.
+
+const (
+	majorUint   = 0
+	majorNegint = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+
+	simpleFalse = 20
+	simpleTrue  = 21
+	simpleNull  = 22
+
+	maxDepth = 16
+)
+
+// cborPair is a CBOR map entry.
+type cborPair struct {
+	key   interface{}
+	value interface{}
+}
+
+// cborMap is a CBOR map, as a list of entries so that it can have integer
+// and text keys.
+type cborMap []cborPair
+
+// get returns the value for the key k, or nil if not present.
+func (m cborMap) get(k interface{}) (interface{}, bool) {
+	for _, p := range m {
+		if p.key == k {
+			return p.value, true
+		}
+	}
+	return nil, false
+}
+
+// cborTag is a tagged CBOR data item.
+type cborTag struct {
+	number  uint64
+	content interface{}
+}
+
+func cborEncode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := cborEncodeTo(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func cborEncodeHead(buf *bytes.Buffer, major byte, n uint64) {
+	var b [9]byte
+	b[0] = major << 5
+	switch {
+	case n < 24:
+		b[0] |= byte(n)
+		buf.Write(b[:1])
+	case n <= 0xff:
+		b[0] |= 24
+		b[1] = byte(n)
+		buf.Write(b[:2])
+	case n <= 0xffff:
+		b[0] |= 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		buf.Write(b[:3])
+	case n <= 0xffffffff:
+		b[0] |= 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		buf.Write(b[:5])
+	default:
+		b[0] |= 27
+		binary.BigEndian.PutUint64(b[1:], n)
+		buf.Write(b[:9])
+	}
+}
+
+func cborEncodeTo(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		cborEncodeHead(buf, majorSimple, simpleNull)
+	case bool:
+		if v {
+			cborEncodeHead(buf, majorSimple, simpleTrue)
+		} else {
+			cborEncodeHead(buf, majorSimple, simpleFalse)
+		}
+	case int:
+		return cborEncodeTo(buf, int64(v))
+	case int64:
+		if v < 0 {
+			cborEncodeHead(buf, majorNegint, uint64(-(v + 1)))
+		} else {
+			cborEncodeHead(buf, majorUint, uint64(v))
+		}
+	case uint64:
+		cborEncodeHead(buf, majorUint, v)
+	case []byte:
+		cborEncodeHead(buf, majorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		cborEncodeHead(buf, majorText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		cborEncodeHead(buf, majorArray, uint64(len(v)))
+		for _, e := range v {
+			if err := cborEncodeTo(buf, e); err != nil {
+				return err
+			}
+		}
+	case cborMap:
+		// Core deterministic encoding: keys sorted by their encoded form.
+		type encPair struct {
+			key, value []byte
+		}
+		pairs := make([]encPair, 0, len(v))
+		for _, p := range v {
+			k, err := cborEncode(p.key)
+			if err != nil {
+				return err
+			}
+			val, err := cborEncode(p.value)
+			if err != nil {
+				return err
+			}
+			pairs = append(pairs, encPair{k, val})
+		}
+		sort.Slice(pairs, func(i, j int) bool { return bytes.Compare(pairs[i].key, pairs[j].key) < 0 })
+		cborEncodeHead(buf, majorMap, uint64(len(pairs)))
+		for i, p := range pairs {
+			if i > 0 && bytes.Equal(p.key, pairs[i-1].key) {
+				return fmt.Errorf("cose: duplicate CBOR map key")
+			}
+			buf.Write(p.key)
+			buf.Write(p.value)
+		}
+	case cborTag:
+		cborEncodeHead(buf, majorTag, v.number)
+		return cborEncodeTo(buf, v.content)
+	default:
+		return fmt.Errorf("cose: unsupported CBOR type: %T", v)
+	}
+	return nil
+}
+
+// cborDecode decodes exactly one CBOR data item from b.  Integers are
+// returned as int64, byte strings as []byte, text strings as string, arrays
+// as []interface{}, maps as cborMap and tags as cborTag.
+func cborDecode(b []byte) (interface{}, error) {
+	d := &cborDecoder{b: b}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.b) != 0 {
+		return nil, fmt.Errorf("cose: trailing data after CBOR item")
+	}
+	return v, nil
+}
+
+type cborDecoder struct {
+	b []byte
+}
+
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if len(d.b) < 1 {
+		return 0, 0, fmt.Errorf("cose: truncated CBOR")
+	}
+	major, info := d.b[0]>>5, d.b[0]&0x1f
+	d.b = d.b[1:]
+
+	var n int
+	switch {
+	case major == majorSimple && info >= 24:
+		return 0, 0, fmt.Errorf("cose: unsupported CBOR float or simple value")
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	default:
+		return 0, 0, fmt.Errorf("cose: unsupported CBOR additional info: %d", info)
+	}
+	if len(d.b) < n {
+		return 0, 0, fmt.Errorf("cose: truncated CBOR")
+	}
+	var v uint64
+	for _, c := range d.b[:n] {
+		v = v<<8 | uint64(c)
+	}
+	d.b = d.b[n:]
+	return major, v, nil
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("cose: CBOR nested too deeply")
+	}
+
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		if n > 1<<63-1 {
+			return nil, fmt.Errorf("cose: CBOR integer overflow")
+		}
+		return int64(n), nil
+	case majorNegint:
+		if n > 1<<63-1 {
+			return nil, fmt.Errorf("cose: CBOR integer overflow")
+		}
+		return -1 - int64(n), nil
+	case majorBytes, majorText:
+		if n > uint64(len(d.b)) {
+			return nil, fmt.Errorf("cose: truncated CBOR")
+		}
+		s := d.b[:n]
+		d.b = d.b[n:]
+		if major == majorText {
+			return string(s), nil
+		}
+		return append([]byte{}, s...), nil
+	case majorArray:
+		// Every item is at least 1 byte, which bounds allocation.
+		if n > uint64(len(d.b)) {
+			return nil, fmt.Errorf("cose: truncated CBOR")
+		}
+		a := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	case majorMap:
+		if n > uint64(len(d.b)/2) {
+			return nil, fmt.Errorf("cose: truncated CBOR")
+		}
+		m := make(cborMap, 0, n)
+		seen := make(map[interface{}]struct{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("cose: unsupported CBOR map key type: %T", k)
+			}
+			if _, ok := seen[k]; ok {
+				return nil, fmt.Errorf("cose: duplicate CBOR map key")
+			}
+			seen[k] = struct{}{}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, cborPair{k, v})
+		}
+		return m, nil
+	case majorTag:
+		v, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{n, v}, nil
+	default:
+		switch n {
+		case simpleFalse:
+			return false, nil
+		case simpleTrue:
+			return true, nil
+		case simpleNull:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cose: unsupported CBOR simple value: %d", n)
+	}
+}
@@ -0,0 +1,104 @@
+// cbor_test.go - Minimal CBOR encoder/decoder tests
+
+package cose
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestCBORVectors(t *testing.T) {
+	// Test vectors from RFC 8949 Appendix A.
+	vectors := []struct {
+		value   interface{}
+		encoded string
+	}{
+		{int64(0), "00"},
+		{int64(23), "17"},
+		{int64(24), "1818"},
+		{int64(100), "1864"},
+		{int64(1000), "1903e8"},
+		{int64(1000000), "1a000f4240"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{int64(-1), "20"},
+		{int64(-10), "29"},
+		{int64(-100), "3863"},
+		{int64(-1000), "3903e7"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{cborTag{1, int64(1363896240)}, "c11a514b67b0"},
+		{[]byte{}, "40"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"", "60"},
+		{"a", "6161"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]interface{}{}, "80"},
+		{[]interface{}{int64(1), int64(2), int64(3)}, "83010203"},
+		{[]interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, "8301820203820405"},
+		{cborMap{}, "a0"},
+		{cborMap{{int64(1), int64(2)}, {int64(3), int64(4)}}, "a201020304"},
+		{cborMap{{"a", int64(1)}, {"b", []interface{}{int64(2), int64(3)}}}, "a26161016162820203"},
+	}
+
+	for i, v := range vectors {
+		b, err := cborEncode(v.value)
+		if err != nil {
+			t.Errorf("[%d]: failed cborEncode(): %s", i, err)
+			continue
+		}
+		if hex.EncodeToString(b) != v.encoded {
+			t.Errorf("[%d]: encoding mismatch: %x", i, b)
+		}
+
+		d, err := cborDecode(b)
+		if err != nil {
+			t.Errorf("[%d]: failed cborDecode(): %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(d, v.value) {
+			t.Errorf("[%d]: decoding mismatch: %#v", i, d)
+		}
+	}
+}
+
+func TestCBORDeterministicMap(t *testing.T) {
+	b, err := cborEncode(cborMap{{int64(-1), int64(0)}, {"z", int64(0)}, {int64(10), int64(0)}, {int64(1), int64(0)}})
+	if err != nil {
+		t.Fatalf("failed cborEncode(): %s", err)
+	}
+	if hex.EncodeToString(b) != "a401000a002000617a00" {
+		t.Errorf("map keys not sorted: %x", b)
+	}
+
+	if _, err = cborEncode(cborMap{{int64(1), int64(0)}, {int64(1), int64(1)}}); err == nil {
+		t.Errorf("cborEncode() accepted duplicate map keys")
+	}
+}
+
+func TestCBORMalformed(t *testing.T) {
+	malformed := []string{
+		"",                                     // Empty.
+		"18",                                   // Truncated argument.
+		"4401",                                 // Truncated byte string.
+		"830102",                               // Truncated array.
+		"a20102",                               // Truncated map.
+		"a201020103",                           // Duplicate map key.
+		"a2616b02616b03",                       // Duplicate text map key.
+		"a20102180103",                         // Non-minimal duplicate map key.
+		"9f01ff",                               // Indefinite length array.
+		"f93c00",                               // Half precision float.
+		"1bffffffffffffffff",                   // Integer overflow.
+		"0000",                                 // Trailing data.
+		"818181818181818181818181818181818100", // Too deep.
+	}
+
+	for i, s := range malformed {
+		b, _ := hex.DecodeString(s)
+		if v, err := cborDecode(b); err == nil {
+			t.Errorf("[%d]: cborDecode() accepted malformed input: %#v", i, v)
+		}
+	}
+}
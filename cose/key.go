@@ -0,0 +1,104 @@
+// key.go - COSE_Key representation of SPHINCS-256 public keys
+
+package cose
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yawning/sphincs256"
+)
+
+const (
+	// KeyTypeOKP is the COSE "kty" used for SPHINCS-256 keys.
+	KeyTypeOKP = 1
+
+	// Curve is the private-use COSE "crv" for SPHINCS-256 keys.
+	Curve = -65537
+
+	keyLabelKty   = 1
+	keyLabelKid   = 2
+	keyLabelAlg   = 3
+	keyLabelCurve = -1
+	keyLabelX     = -2
+)
+
+// Key is a COSE_Key holding a SPHINCS-256 public key.
+type Key struct {
+	// KeyID is the optional "kid", which must be KeyID(PublicKey) if set.
+	KeyID     []byte
+	PublicKey *[sphincs256.PublicKeySize]byte
+}
+
+// NewKey returns a Key for publicKey, with the "kid" set.
+func NewKey(publicKey *[sphincs256.PublicKeySize]byte) *Key {
+	return &Key{KeyID: KeyID(publicKey), PublicKey: publicKey}
+}
+
+// KeyID returns the "kid" of publicKey, the raw bytes of its
+// sphincs256.KeyID.
+func KeyID(publicKey *[sphincs256.PublicKeySize]byte) []byte {
+	id := (*sphincs256.PublicKey)(publicKey).KeyID()
+	return id[:]
+}
+
+// Bytes returns the CBOR encoded COSE_Key.
+func (k *Key) Bytes() []byte {
+	m := cborMap{
+		{int64(keyLabelKty), int64(KeyTypeOKP)},
+		{int64(keyLabelAlg), int64(AlgorithmID)},
+		{int64(keyLabelCurve), int64(Curve)},
+		{int64(keyLabelX), k.PublicKey[:]},
+	}
+	if k.KeyID != nil {
+		m = append(m, cborPair{int64(keyLabelKid), k.KeyID})
+	}
+
+	// Encoding can only fail on unsupported types, which never happens here.
+	b, err := cborEncode(m)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// ParseKey decodes a CBOR encoded COSE_Key holding a SPHINCS-256 public key.
+func ParseKey(b []byte) (*Key, error) {
+	v, err := cborDecode(b)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(cborMap)
+	if !ok {
+		return nil, fmt.Errorf("cose: malformed COSE_Key")
+	}
+
+	if kty, _ := m.get(int64(keyLabelKty)); kty != int64(KeyTypeOKP) {
+		return nil, fmt.Errorf("cose: unsupported COSE_Key \"kty\"")
+	}
+	if crv, _ := m.get(int64(keyLabelCurve)); crv != int64(Curve) {
+		return nil, fmt.Errorf("cose: unsupported COSE_Key \"crv\"")
+	}
+	if alg, ok := m.get(int64(keyLabelAlg)); ok && alg != int64(AlgorithmID) {
+		return nil, ErrInvalidAlgorithm
+	}
+
+	k := new(Key)
+	x, _ := m.get(int64(keyLabelX))
+	pk, ok := x.([]byte)
+	if !ok || len(pk) != sphincs256.PublicKeySize {
+		return nil, fmt.Errorf("cose: malformed COSE_Key \"x\"")
+	}
+	k.PublicKey = new([sphincs256.PublicKeySize]byte)
+	copy(k.PublicKey[:], pk)
+
+	if kid, ok := m.get(int64(keyLabelKid)); ok {
+		if k.KeyID, ok = kid.([]byte); !ok {
+			return nil, fmt.Errorf("cose: malformed COSE_Key \"kid\"")
+		}
+		if !bytes.Equal(k.KeyID, KeyID(k.PublicKey)) {
+			return nil, ErrKeyIDMismatch
+		}
+	}
+	return k, nil
+}
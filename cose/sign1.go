@@ -0,0 +1,201 @@
+// sign1.go - COSE_Sign1 (RFC 9052) using SPHINCS-256
+
+// Package cose implements COSE_Sign1 (RFC 9052) single signer structures and
+// COSE_Key representations for the SPHINCS-256 signature scheme.
+//
+// SPHINCS-256 does not have a registered COSE algorithm identifier, so a
+// private-use value is used, and only structures that carry it are accepted.
+// A small built-in CBOR encoder/decoder is used, which supports just what is
+// needed for COSE.
+package cose
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/yawning/sphincs256"
+)
+
+const (
+	// AlgorithmID is the private-use COSE algorithm identifier for
+	// SPHINCS-256.
+	AlgorithmID = -65537
+
+	// Sign1Tag is the CBOR tag for COSE_Sign1.
+	Sign1Tag = 18
+
+	headerAlgorithm   = 1
+	headerCritical    = 2
+	headerContentType = 3
+	headerKeyID       = 4
+
+	sign1Context = "Signature1"
+)
+
+var (
+	// ErrInvalidAlgorithm is the error returned when a COSE_Sign1 protected
+	// header does not specify AlgorithmID.
+	ErrInvalidAlgorithm = errors.New("cose: unsupported algorithm")
+
+	// ErrKeyIDMismatch is the error returned when a "kid" is not the key ID
+	// of the public key.
+	ErrKeyIDMismatch = errors.New("cose: \"kid\" does not match the public key")
+
+	// ErrVerificationFailed is the error returned when a COSE_Sign1
+	// signature is invalid.
+	ErrVerificationFailed = errors.New("cose: signature verification failed")
+)
+
+// Sign1Message is a COSE_Sign1 message.
+type Sign1Message struct {
+	// ContentType is the optional protected content type header.
+	ContentType string
+
+	// KeyID is the unprotected key identifier header, which Sign1 sets to
+	// the signing key's sphincs256.KeyID.
+	KeyID []byte
+
+	// Payload is the payload.  Even when Detached is set, the payload is
+	// still what gets signed.
+	Payload []byte
+
+	// Detached omits the payload from the encoded COSE_Sign1, so that it
+	// must be supplied out-of-band to verify the signature.
+	Detached bool
+}
+
+// Sign1 signs msg with privateKey, binding the optional externalAAD, and
+// returns the tagged CBOR encoded COSE_Sign1 structure.
+func Sign1(privateKey *[sphincs256.PrivateKeySize]byte, msg *Sign1Message, externalAAD []byte) ([]byte, error) {
+	protected := cborMap{{int64(headerAlgorithm), int64(AlgorithmID)}}
+	if msg.ContentType != "" {
+		protected = append(protected, cborPair{int64(headerContentType), msg.ContentType})
+	}
+	encProtected, err := cborEncode(protected)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := KeyID(sphincs256.PublicKeyFromPrivateKey(privateKey))
+	unprotected := cborMap{{int64(headerKeyID), kid}}
+
+	tbs, err := sigStructure(encProtected, externalAAD, msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+	sig := sphincs256.Sign(privateKey, tbs)
+
+	var payload interface{}
+	if !msg.Detached {
+		payload = nonNil(msg.Payload)
+	}
+	return cborEncode(cborTag{Sign1Tag, []interface{}{encProtected, unprotected, payload, sig[:]}})
+}
+
+// VerifySign1 decodes a (optionally tagged) COSE_Sign1 structure and verifies
+// it with publicKey and the optional externalAAD.  If the payload is
+// detached, it must be provided as detachedPayload, which must otherwise be
+// nil.  The decoded message is returned iff the signature is valid.
+func VerifySign1(publicKey *[sphincs256.PublicKeySize]byte, data, externalAAD, detachedPayload []byte) (*Sign1Message, error) {
+	v, err := cborDecode(data)
+	if err != nil {
+		return nil, err
+	}
+	if tag, ok := v.(cborTag); ok {
+		if tag.number != Sign1Tag {
+			return nil, fmt.Errorf("cose: unexpected CBOR tag: %d", tag.number)
+		}
+		v = tag.content
+	}
+	a, ok := v.([]interface{})
+	if !ok || len(a) != 4 {
+		return nil, fmt.Errorf("cose: malformed COSE_Sign1")
+	}
+	encProtected, ok1 := a[0].([]byte)
+	unprotected, ok2 := a[1].(cborMap)
+	rawSig, ok3 := a[3].([]byte)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf("cose: malformed COSE_Sign1")
+	}
+
+	msg := new(Sign1Message)
+
+	// Validate the protected header.
+	if len(encProtected) == 0 {
+		return nil, ErrInvalidAlgorithm
+	}
+	v, err = cborDecode(encProtected)
+	if err != nil {
+		return nil, err
+	}
+	protected, ok := v.(cborMap)
+	if !ok {
+		return nil, fmt.Errorf("cose: malformed protected header")
+	}
+	if alg, _ := protected.get(int64(headerAlgorithm)); alg != int64(AlgorithmID) {
+		return nil, ErrInvalidAlgorithm
+	}
+	if _, ok = protected.get(int64(headerCritical)); ok {
+		return nil, fmt.Errorf("cose: unsupported critical header")
+	}
+	if ct, ok := protected.get(int64(headerContentType)); ok {
+		if msg.ContentType, ok = ct.(string); !ok {
+			return nil, fmt.Errorf("cose: unsupported content type header")
+		}
+	}
+	if _, ok = unprotected.get(int64(headerAlgorithm)); ok {
+		return nil, fmt.Errorf("cose: algorithm in unprotected header")
+	}
+	if kid, ok := unprotected.get(int64(headerKeyID)); ok {
+		if msg.KeyID, ok = kid.([]byte); !ok {
+			return nil, fmt.Errorf("cose: malformed key identifier header")
+		}
+		if !bytes.Equal(msg.KeyID, KeyID(publicKey)) {
+			return nil, ErrKeyIDMismatch
+		}
+	}
+
+	switch payload := a[2].(type) {
+	case nil:
+		if detachedPayload == nil {
+			return nil, fmt.Errorf("cose: detached payload not provided")
+		}
+		msg.Payload, msg.Detached = detachedPayload, true
+	case []byte:
+		if detachedPayload != nil {
+			return nil, fmt.Errorf("cose: detached payload provided for attached COSE_Sign1")
+		}
+		msg.Payload = payload
+	default:
+		return nil, fmt.Errorf("cose: malformed COSE_Sign1 payload")
+	}
+
+	if len(rawSig) != sphincs256.SignatureSize {
+		return nil, ErrVerificationFailed
+	}
+	var sig [sphincs256.SignatureSize]byte
+	copy(sig[:], rawSig)
+
+	tbs, err := sigStructure(encProtected, externalAAD, msg.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if !sphincs256.Verify(publicKey, tbs, &sig) {
+		return nil, ErrVerificationFailed
+	}
+	return msg, nil
+}
+
+// sigStructure returns the encoded Sig_structure for a COSE_Sign1.
+func sigStructure(encProtected, externalAAD, payload []byte) ([]byte, error) {
+	return cborEncode([]interface{}{sign1Context, encProtected, nonNil(externalAAD), nonNil(payload)})
+}
+
+// nonNil ensures that b is encoded as a (possibly empty) byte string.
+func nonNil(b []byte) []byte {
+	if b == nil {
+		return []byte{}
+	}
+	return b
+}
@@ -0,0 +1,140 @@
+// sign1_test.go - COSE_Sign1 and COSE_Key tests
+
+package cose
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+)
+
+var (
+	testPayload = []byte("firmware-manifest: dunwich-0.1.2")
+	testAAD     = []byte("device-class: whateley")
+)
+
+func TestSign1(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	msg := &Sign1Message{
+		ContentType: "application/octet-stream",
+		KeyID:       []byte("11"),
+		Payload:     testPayload,
+	}
+	b, err := Sign1(sk, msg, testAAD)
+	if err != nil {
+		t.Fatalf("failed Sign1(): %s", err)
+	}
+
+	m, err := VerifySign1(pk, b, testAAD, nil)
+	if err != nil {
+		t.Fatalf("failed VerifySign1(): %s", err)
+	}
+	if !bytes.Equal(m.Payload, testPayload) || m.ContentType != msg.ContentType || !bytes.Equal(m.KeyID, KeyID(pk)) || m.Detached {
+		t.Errorf("message mismatch: %+v", m)
+	}
+
+	otherPk, _, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	if _, err = VerifySign1(otherPk, b, testAAD, nil); err != ErrKeyIDMismatch {
+		t.Errorf("VerifySign1() with another key: %v", err)
+	}
+
+	if _, err = VerifySign1(pk, b, nil, nil); err != ErrVerificationFailed {
+		t.Errorf("VerifySign1() with the wrong external AAD: %v", err)
+	}
+	if _, err = VerifySign1(pk, b, testAAD, testPayload); err == nil {
+		t.Errorf("VerifySign1() accepted a detached payload for an attached message")
+	}
+}
+
+func TestSign1Detached(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	b, err := Sign1(sk, &Sign1Message{Payload: testPayload, Detached: true}, nil)
+	if err != nil {
+		t.Fatalf("failed Sign1(): %s", err)
+	}
+	if bytes.Contains(b, testPayload) {
+		t.Fatalf("detached COSE_Sign1 contains the payload")
+	}
+
+	m, err := VerifySign1(pk, b, nil, testPayload)
+	if err != nil {
+		t.Fatalf("failed VerifySign1(): %s", err)
+	}
+	if !m.Detached || !bytes.Equal(m.Payload, testPayload) {
+		t.Errorf("message mismatch: %+v", m)
+	}
+
+	if _, err = VerifySign1(pk, b, nil, nil); err == nil {
+		t.Errorf("VerifySign1() succeeded without the detached payload")
+	}
+	if _, err = VerifySign1(pk, b, nil, testPayload[1:]); err != ErrVerificationFailed {
+		t.Errorf("VerifySign1() with the wrong detached payload: %v", err)
+	}
+}
+
+func TestSign1WrongAlgorithm(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	b, err := Sign1(sk, &Sign1Message{Payload: testPayload}, nil)
+	if err != nil {
+		t.Fatalf("failed Sign1(): %s", err)
+	}
+
+	// Re-encode with the EdDSA algorithm identifier, reusing the signature.
+	v, _ := cborDecode(b)
+	a := v.(cborTag).content.([]interface{})
+	for _, alg := range []int64{-8, -65536} {
+		a[0], _ = cborEncode(cborMap{{int64(headerAlgorithm), alg}})
+		forged, _ := cborEncode(a)
+		if _, err = VerifySign1(pk, forged, nil, nil); err != ErrInvalidAlgorithm {
+			t.Errorf("VerifySign1() with alg %d: %v", alg, err)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	pk, _, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	k := NewKey(pk)
+	k2, err := ParseKey(k.Bytes())
+	if err != nil {
+		t.Fatalf("failed ParseKey(): %s", err)
+	}
+	if *k2.PublicKey != *pk || !bytes.Equal(k2.KeyID, k.KeyID) {
+		t.Errorf("COSE_Key round trip mismatch")
+	}
+	if id := (*sphincs256.PublicKey)(pk).KeyID(); !bytes.Equal(k.KeyID, id[:]) {
+		t.Errorf("unexpected \"kid\": %x", k.KeyID)
+	}
+	if _, err = ParseKey((&Key{KeyID: []byte("11"), PublicKey: pk}).Bytes()); err != ErrKeyIDMismatch {
+		t.Errorf("ParseKey() with the wrong \"kid\": %v", err)
+	}
+
+	b, _ := cborEncode(cborMap{
+		{int64(keyLabelKty), int64(KeyTypeOKP)},
+		{int64(keyLabelCurve), int64(6)}, // Ed25519
+		{int64(keyLabelX), pk[:]},
+	})
+	if _, err = ParseKey(b); err == nil {
+		t.Errorf("ParseKey() accepted the wrong curve")
+	}
+}
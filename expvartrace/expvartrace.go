@@ -0,0 +1,91 @@
+// expvartrace.go - expvar signing metrics
+
+// Package expvartrace implements a sphincs256.Tracer that exports signing
+// events as expvar metrics, served as JSON under /debug/vars by the expvar
+// package's HTTP handler.
+package expvartrace
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+
+	"github.com/yawning/sphincs256"
+	"github.com/yawning/sphincs256/hash"
+)
+
+// Tracer is a sphincs256.Tracer that accumulates the signing events into an
+// expvar.Map with the following keys:
+//
+//	signatures  - the number of completed signatures
+//	in_flight   - the number of signatures currently being computed
+//	sign_ns     - the total time spent signing, in nanoseconds
+//	horst_ns    - the total time spent in HORST, in nanoseconds
+//	layer_ns    - the total time spent per hypertree layer, keyed by level
+//	hash        - the hash.Counters, if hash.CountersEnabled
+//
+// The per signature averages are the totals divided by signatures.  The hash
+// function invocation counts are process wide, so they include signatures
+// that did not use the Tracer.  It is safe for concurrent use.
+type Tracer struct {
+	m *expvar.Map
+
+	signatures expvar.Int
+	inFlight   expvar.Int
+	signNs     expvar.Int
+	horstNs    expvar.Int
+	layerNs    expvar.Map
+}
+
+// New creates a Tracer and publishes its metrics under name.  Like
+// expvar.Publish, it panics if name is already in use.
+func New(name string) *Tracer {
+	t := &Tracer{m: new(expvar.Map)}
+	t.m.Set("signatures", &t.signatures)
+	t.m.Set("in_flight", &t.inFlight)
+	t.m.Set("sign_ns", &t.signNs)
+	t.m.Set("horst_ns", &t.horstNs)
+	t.m.Set("layer_ns", &t.layerNs)
+	if hash.CountersEnabled {
+		t.m.Set("hash", expvar.Func(func() interface{} {
+			return hash.ReadCounters()
+		}))
+	}
+	expvar.Publish(name, t.m)
+	return t
+}
+
+// Map returns the expvar.Map holding the Tracer's metrics.
+func (t *Tracer) Map() *expvar.Map {
+	return t.m
+}
+
+// OnSignStart implements sphincs256.Tracer.
+func (t *Tracer) OnSignStart() {
+	t.inFlight.Add(1)
+}
+
+// OnSignEnd implements sphincs256.Tracer.
+func (t *Tracer) OnSignEnd(elapsed time.Duration) {
+	t.inFlight.Add(-1)
+	t.signatures.Add(1)
+	t.signNs.Add(int64(elapsed))
+}
+
+// OnHORSTStart implements sphincs256.Tracer.
+func (t *Tracer) OnHORSTStart() {}
+
+// OnHORSTEnd implements sphincs256.Tracer.
+func (t *Tracer) OnHORSTEnd(elapsed time.Duration) {
+	t.horstNs.Add(int64(elapsed))
+}
+
+// OnLayerStart implements sphincs256.Tracer.
+func (t *Tracer) OnLayerStart(level int) {}
+
+// OnLayerEnd implements sphincs256.Tracer.
+func (t *Tracer) OnLayerEnd(level int, elapsed time.Duration) {
+	t.layerNs.Add(strconv.Itoa(level), int64(elapsed))
+}
+
+var _ sphincs256.Tracer = (*Tracer)(nil)
@@ -0,0 +1,54 @@
+// expvartrace_test.go - expvar signing metrics tests
+
+package expvartrace
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+	"github.com/yawning/sphincs256/hash"
+)
+
+func TestTracer(t *testing.T) {
+	_, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	tr := New("sphincs256_test")
+	if expvar.Get("sphincs256_test") != tr.Map() {
+		t.Fatalf("metrics not published")
+	}
+
+	opts := &sphincs256.SignOptions{Tracer: tr}
+	for i := 0; i < 2; i++ {
+		sphincs256.SignWithOptions(sk, nil, opts)
+	}
+
+	var metrics struct {
+		Signatures int64            `json:"signatures"`
+		InFlight   int64            `json:"in_flight"`
+		SignNs     int64            `json:"sign_ns"`
+		HORSTNs    int64            `json:"horst_ns"`
+		LayerNs    map[string]int64 `json:"layer_ns"`
+		Hash       hash.Counters    `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(tr.Map().String()), &metrics); err != nil {
+		t.Fatalf("failed to parse metrics: %s", err)
+	}
+	if metrics.Signatures != 2 || metrics.InFlight != 0 {
+		t.Fatalf("unexpected signature counts: %+v", metrics)
+	}
+	if metrics.HORSTNs <= 0 || metrics.SignNs < metrics.HORSTNs {
+		t.Fatalf("unexpected timings: %+v", metrics)
+	}
+	if len(metrics.LayerNs) != 12 {
+		t.Fatalf("unexpected number of layers: %v", metrics.LayerNs)
+	}
+	if hash.CountersEnabled != (metrics.Hash.Hash_n_n != 0) {
+		t.Fatalf("unexpected hash counters: %+v", metrics.Hash)
+	}
+}
@@ -0,0 +1,180 @@
+// fingerprint.go - SPHINCS-256 public key fingerprints and key IDs
+
+package sphincs256
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/yawning/sphincs256/hash"
+)
+
+const (
+	// FingerprintSize is the length of a public key fingerprint in bytes.
+	FingerprintSize = hash.Size
+
+	// KeyIDSize is the length of a key ID in bytes.
+	KeyIDSize = 8
+
+	fingerprintContext = "SPHINCS-256 public key fingerprint\x00"
+	fingerprintPrefix  = "BLAKE256:"
+)
+
+// PublicKey is a SPHINCS-256 public key.  A *[PublicKeySize]byte as returned
+// by GenerateKey can be converted to a *PublicKey for access to the methods.
+type PublicKey [PublicKeySize]byte
+
+// Fingerprint returns the public key's fingerprint, a domain separated
+// BLAKE-256 digest of the public key.
+func (k *PublicKey) Fingerprint() *Fingerprint {
+	var buf bytes.Buffer
+	buf.WriteString(fingerprintContext)
+	buf.Write(k[:])
+
+	f := new(Fingerprint)
+	hash.Varlen(f[:], buf.Bytes())
+	return f
+}
+
+// KeyID returns the public key's key ID.
+func (k *PublicKey) KeyID() KeyID {
+	return k.Fingerprint().KeyID()
+}
+
+// Fingerprint is a SPHINCS-256 public key fingerprint.
+type Fingerprint [FingerprintSize]byte
+
+// KeyID returns the key ID, the leading KeyIDSize bytes of the fingerprint.
+func (f *Fingerprint) KeyID() KeyID {
+	var id KeyID
+	copy(id[:], f[:])
+	return id
+}
+
+// Hex returns the fingerprint as a lower case hexadecimal string.
+func (f *Fingerprint) Hex() string {
+	return hex.EncodeToString(f[:])
+}
+
+// Base64 returns the fingerprint as an unpadded base64 string.
+func (f *Fingerprint) Base64() string {
+	return base64.RawStdEncoding.EncodeToString(f[:])
+}
+
+// String returns the fingerprint as an OpenSSH style "BLAKE256:<base64>"
+// string.
+func (f *Fingerprint) String() string {
+	return fingerprintPrefix + f.Base64()
+}
+
+// Randomart returns an OpenSSH style "drunken bishop" visualization of the
+// fingerprint.
+func (f *Fingerprint) Randomart() string {
+	const (
+		width   = 17
+		height  = 9
+		augment = " .o+=*BOX@%&#/^SE"
+		start   = len(augment) - 2
+		end     = len(augment) - 1
+	)
+
+	var field [width][height]int
+	x, y := width/2, height/2
+	for _, b := range f {
+		// Each byte conveys 4 moves, least significant bits first.
+		for i := 0; i < 4; i++ {
+			if b&1 != 0 {
+				x++
+			} else {
+				x--
+			}
+			if b&2 != 0 {
+				y++
+			} else {
+				y--
+			}
+			x = clamp(x, 0, width-1)
+			y = clamp(y, 0, height-1)
+			if field[x][y] < start-1 {
+				field[x][y]++
+			}
+			b >>= 2
+		}
+	}
+	field[width/2][height/2] = start
+	field[x][y] = end
+
+	var buf bytes.Buffer
+	buf.WriteString(randomartBorder("[SPHINCS256]", width))
+	for y := 0; y < height; y++ {
+		buf.WriteByte('|')
+		for x := 0; x < width; x++ {
+			buf.WriteByte(augment[field[x][y]])
+		}
+		buf.WriteString("|\n")
+	}
+	buf.WriteString(randomartBorder("[BLAKE256]", width))
+	return buf.String()
+}
+
+func randomartBorder(title string, width int) string {
+	pad := width - len(title)
+	return "+" + dashes(pad/2) + title + dashes(pad-pad/2) + "+\n"
+}
+
+func dashes(n int) string {
+	return string(bytes.Repeat([]byte{'-'}, n))
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// KeyID is a short SPHINCS-256 public key identifier, suitable for pinning
+// keys in configuration files and naming keys in file formats.  It is only
+// meant to distinguish between keys, not to authenticate them, for which the
+// full Fingerprint should be used.
+//
+// It is the key identifier in every format in this module: the sigfile,
+// COSE "kid" and X.509 key identifiers carry the raw bytes, and the JOSE
+// "kid" and OpenSSH tooling use the String form.
+type KeyID [KeyIDSize]byte
+
+// String returns the key ID as an upper case hexadecimal string.
+func (id KeyID) String() string {
+	return fmt.Sprintf("%X", id[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (id KeyID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (id *KeyID) UnmarshalText(text []byte) error {
+	parsed, err := ParseKeyID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// ParseKeyID parses a hexadecimal key ID, as returned by KeyID.String.
+func ParseKeyID(s string) (KeyID, error) {
+	var id KeyID
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != KeyIDSize {
+		return id, fmt.Errorf("sphincs256: invalid key ID: %q", s)
+	}
+	copy(id[:], b)
+	return id, nil
+}
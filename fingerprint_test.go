@@ -0,0 +1,93 @@
+// fingerprint_test.go - SPHINCS-256 fingerprint and key ID tests
+
+package sphincs256
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	pk, _, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	otherPk, _, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	f := (*PublicKey)(pk).Fingerprint()
+	if *f != *(*PublicKey)(pk).Fingerprint() {
+		t.Fatalf("Fingerprint() is not deterministic")
+	}
+	if *f == *(*PublicKey)(otherPk).Fingerprint() {
+		t.Fatalf("Fingerprint() collision between distinct keys")
+	}
+
+	if b, err := hex.DecodeString(f.Hex()); err != nil || string(b) != string(f[:]) {
+		t.Errorf("Hex() does not round trip")
+	}
+	if b, err := base64.RawStdEncoding.DecodeString(f.Base64()); err != nil || string(b) != string(f[:]) {
+		t.Errorf("Base64() does not round trip")
+	}
+	if f.String() != "BLAKE256:"+f.Base64() {
+		t.Errorf("unexpected String(): %s", f.String())
+	}
+
+	art := f.Randomart()
+	lines := strings.Split(strings.TrimSuffix(art, "\n"), "\n")
+	if len(lines) != 11 {
+		t.Fatalf("Randomart() has %d lines", len(lines))
+	}
+	for i, l := range lines {
+		if len(l) != 19 {
+			t.Errorf("Randomart() line %d has length %d: %q", i, len(l), l)
+		}
+	}
+	if lines[0] != "+--[SPHINCS256]---+" || lines[10] != "+---[BLAKE256]----+" {
+		t.Errorf("unexpected Randomart() borders:\n%s", art)
+	}
+	if strings.Count(strings.Join(lines[1:10], ""), "E") != 1 || (lines[5][9] != 'S' && lines[5][9] != 'E') {
+		t.Errorf("Randomart() start/end markers are wrong:\n%s", art)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	pk, _, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	id := (*PublicKey)(pk).KeyID()
+	f := (*PublicKey)(pk).Fingerprint()
+	if string(id[:]) != string(f[:KeyIDSize]) {
+		t.Fatalf("KeyID() is not a prefix of Fingerprint()")
+	}
+
+	s := id.String()
+	if len(s) != 2*KeyIDSize || strings.ToUpper(s) != s {
+		t.Errorf("unexpected String(): %s", s)
+	}
+
+	var id2 KeyID
+	text, _ := id.MarshalText()
+	if err = id2.UnmarshalText(text); err != nil {
+		t.Fatalf("failed UnmarshalText(): %s", err)
+	}
+	if id2 != id {
+		t.Errorf("KeyID text round trip mismatch")
+	}
+	if id2, err = ParseKeyID(strings.ToLower(s)); err != nil || id2 != id {
+		t.Errorf("ParseKeyID() failed on lower case input")
+	}
+
+	for _, bad := range []string{"", s[:len(s)-2], s + "00", "XX" + s[2:]} {
+		if _, err = ParseKeyID(bad); err == nil {
+			t.Errorf("ParseKeyID() accepted %q", bad)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+// counters.go - Hash function invocation counters
+
+package hash
+
+// Counters are the number of times each hash function has been invoked,
+// across all goroutines.  The counters are only compiled in when building
+// with the "sphincs256_hashcounters" tag (see CountersEnabled), so that
+// ordinary builds do not pay for them on every hash function invocation.
+// Since they are process wide, concurrent signers see each other's counts.
+type Counters struct {
+	Varlen    uint64
+	Hash_n_n  uint64 // Including Hash_n_n_mask.
+	Hash_2n_n uint64 // Including Hash_2n_n_mask.
+}
+
+var counters Counters
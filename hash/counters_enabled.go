@@ -0,0 +1,26 @@
+// counters_enabled.go - Hash function invocation counters (enabled)
+
+// +build sphincs256_hashcounters
+
+package hash
+
+import (
+	"sync/atomic"
+)
+
+// CountersEnabled is true iff the hash function invocation counters are
+// compiled in.
+const CountersEnabled = true
+
+// ReadCounters returns the current hash function invocation counts.
+func ReadCounters() Counters {
+	return Counters{
+		Varlen:    atomic.LoadUint64(&counters.Varlen),
+		Hash_n_n:  atomic.LoadUint64(&counters.Hash_n_n),
+		Hash_2n_n: atomic.LoadUint64(&counters.Hash_2n_n),
+	}
+}
+
+func count(c *uint64) {
+	atomic.AddUint64(c, 1)
+}
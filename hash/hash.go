@@ -9,6 +9,8 @@
 package hash
 
 import (
+	stdhash "hash"
+
 	"github.com/dchest/blake256"
 
 	"github.com/yawning/sphincs256/chacha"
@@ -21,14 +23,42 @@ const (
 )
 
 func Varlen(out, in []byte) {
-	h := blake256.New()
-	h.Write(in)
-	tmp := h.Sum(nil)
-	copy(out[:], tmp[:])
-	utils.Zerobytes(tmp[:])
+	var v VarlenHasher
+	v.Varlen(out, in)
+}
+
+// VarlenHasher computes Varlen, reusing its state across calls so that it
+// does not allocate after the first call.  The zero value is ready for use.
+// A VarlenHasher must not be used concurrently.
+//
+// Note: The BLAKE-256 digest provides no way to wipe its internal state, so
+// the tail of the last input stays in memory until the next call.
+type VarlenHasher struct {
+	h   stdhash.Hash
+	buf [blake256.BlockSize]byte
+}
+
+// Varlen is equivalent to the Varlen function.
+func (v *VarlenHasher) Varlen(out, in []byte) {
+	count(&counters.Varlen)
+	if v.h == nil {
+		v.h = blake256.New()
+	}
+	v.h.Reset()
+
+	// Feed the input in via buf, so that 'in' does not escape to the heap.
+	for rem := in; len(rem) > 0; {
+		n := copy(v.buf[:], rem)
+		v.h.Write(v.buf[:n])
+		rem = rem[n:]
+	}
+	sum := v.h.Sum(v.buf[:0])
+	copy(out[:Size], sum)
+	utils.Zerobytes(v.buf[:])
 }
 
 func Hash_2n_n(out, in []byte) {
+	count(&counters.Hash_2n_n)
 	var x [64]byte
 	for i := 0; i < 32; i++ {
 		x[i] = in[i]
@@ -51,6 +81,7 @@ func Hash_2n_n_mask(out, in, mask []byte) {
 }
 
 func Hash_n_n(out, in []byte) {
+	count(&counters.Hash_n_n)
 	var x [64]byte
 	for i := 0; i < 32; i++ {
 		x[i] = in[i]
@@ -58,6 +89,9 @@ func Hash_n_n(out, in []byte) {
 	}
 	chacha.Permute(&x)
 	copy(out[:Size], x[:])
+
+	// Hash_n_n is used on secret values (HORST leaves and WOTS chains).
+	utils.Zerobytes(x[:])
 }
 
 func Hash_n_n_mask(out, in, mask []byte) {
@@ -66,6 +100,7 @@ func Hash_n_n_mask(out, in, mask []byte) {
 		buf[i] = in[i] ^ mask[i]
 	}
 	Hash_n_n(out, buf[:])
+	utils.Zerobytes(buf[:])
 }
 
 func init() {
@@ -3,8 +3,12 @@
 package horst
 
 import (
+	"sync"
+	"sync/atomic"
+
 	"github.com/yawning/sphincs256/chacha"
 	"github.com/yawning/sphincs256/hash"
+	"github.com/yawning/sphincs256/securemem"
 	"github.com/yawning/sphincs256/utils"
 
 	"github.com/dchest/blake512"
@@ -18,32 +22,67 @@ const (
 	K        = 32
 	SkBytes  = 32
 	SigBytes = 64*hash.Size + (((LogT-6)*hash.Size)+SkBytes)*K
+
+	leafChunk     = 64 // Secret key elements expanded at a time while signing.
+	subtreeHeight = 10 // Height of the independently built subtrees.
+	nSubtrees     = T >> subtreeHeight
 )
 
-func expandSeed(outseeds []byte, inseed *[SeedBytes]byte) {
-//	outseeds = outseeds[:T*SkBytes]
-	chacha.Prg(outseeds[0:T*SkBytes], inseed[:])
+// Scratch is reusable working memory for Sign.  The zero value is ready for
+// use.  A Scratch must not be used concurrently.
+type Scratch struct {
+	tree  [(2*T - 1) * hash.Size]byte
+	skBuf *securemem.Buffer
+}
+
+// Destroy releases the secure memory held by the Scratch.
+func (s *Scratch) Destroy() {
+	if s.skBuf != nil {
+		s.skBuf.Destroy()
+		s.skBuf = nil
+	}
 }
 
-func Sign(sig []byte, pk *[hash.Size]byte, m []byte, seed *[SeedBytes]byte, masks []byte, mHash []byte) {
+// Sign generates a HORST signature and public key, using scratch as working
+// memory (if scratch is nil, it is allocated).  The tree is built as 64
+// independent subtrees rooted at level 10, on up to concurrency goroutines
+// (if concurrency <= 1, on the calling goroutine only).  The output does not
+// depend on concurrency.
+func Sign(sig []byte, pk *[hash.Size]byte, m []byte, seed *[SeedBytes]byte, masks []byte, mHash []byte, concurrency int, scratch *Scratch) {
 //	masks = masks[:2*LogT*hash.Size]
 //	mHash = mHash[:hash.MsgSize]
 
-	var sk [T * SkBytes]byte
 	sigpos := 0
-
-	expandSeed(sk[:], seed)
+	if scratch == nil {
+		scratch = new(Scratch)
+		defer scratch.Destroy()
+	}
 
 	// Build the whole tree and save it.
-	var tree [(2*T - 1) * hash.Size]byte // replace by something more memory-efficient?
+	tree := scratch.tree[:] // replace by something more memory-efficient?
 
-	// Generate pk leaves.
-	for i := 0; i < T; i++ {
-		hash.Hash_n_n(tree[(T-1+i)*hash.Size:], sk[i*SkBytes:])
+	// Build the subtrees below level 10.
+	if concurrency > nSubtrees {
+		concurrency = nSubtrees
+	}
+	if concurrency <= 1 {
+		// The fully expanded secret key is 2 MiB, so expand it a chunk at
+		// a time into secure memory instead, and only regenerate the
+		// elements that are revealed.
+		if scratch.skBuf == nil {
+			scratch.skBuf = securemem.New(leafChunk * SkBytes)
+		}
+		for s := 0; s < nSubtrees; s++ {
+			buildSubtree(tree, s, seed, masks, scratch.skBuf.Bytes())
+		}
+		scratch.skBuf.Zero()
+	} else {
+		buildSubtreesConcurrent(tree, seed, masks, concurrency)
 	}
 
+	// Build the top of the tree, from level 10 up.
 	var offsetIn, offsetOut uint64
-	for i := uint(0); i < LogT; i++ {
+	for i := uint(subtreeHeight); i < LogT; i++ {
 		offsetIn = (1 << (LogT - i)) - 1
 		offsetOut = (1 << (LogT - i - 1)) - 1
 		for j := uint64(0); j < 1<<(LogT-i-1); j++ {
@@ -60,7 +99,7 @@ func Sign(sig []byte, pk *[hash.Size]byte, m []byte, seed *[SeedBytes]byte, mask
 	for i := 0; i < K; i++ {
 		idx := uint(mHash[2*i]) + (uint(mHash[2*i+1]) << 8)
 
-		copy(sig[sigpos:sigpos+SkBytes], sk[idx*SkBytes:(idx+1)*SkBytes])
+		chacha.PrgAt(sig[sigpos:sigpos+SkBytes], seed[:], uint64(idx)*SkBytes)
 		sigpos += SkBytes
 
 		idx += T - 1
@@ -78,6 +117,66 @@ func Sign(sig []byte, pk *[hash.Size]byte, m []byte, seed *[SeedBytes]byte, mask
 	}
 
 	copy(pk[0:hash.Size], tree[0:hash.Size])
+
+	// The tree is derived from the secret key, and most of it is never
+	// revealed.
+	utils.Zerobytes(tree)
+}
+
+// buildSubtreesConcurrent builds all of the subtrees, on concurrency
+// goroutines.
+func buildSubtreesConcurrent(tree []byte, seed *[SeedBytes]byte, masks []byte, concurrency int) {
+	// Work on copies of seed and masks, so that the caller's copies do not
+	// escape to the heap when signing on a single goroutine.
+	seedCopy := new([SeedBytes]byte)
+	copy(seedCopy[:], seed[:])
+	masksCopy := make([]byte, len(masks))
+	copy(masksCopy, masks)
+
+	var next uint32
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func() {
+			defer wg.Done()
+
+			skBuf := securemem.New(leafChunk * SkBytes)
+			defer skBuf.Destroy()
+			for {
+				s := int(atomic.AddUint32(&next, 1)) - 1
+				if s >= nSubtrees {
+					break
+				}
+				buildSubtree(tree, s, seedCopy, masksCopy, skBuf.Bytes())
+			}
+			skBuf.Zero()
+		}()
+	}
+	wg.Wait()
+	utils.Zerobytes(seedCopy[:])
+}
+
+// buildSubtree builds the s-th subtree, using sk (leafChunk*SkBytes) as
+// scratch space for the secret key elements.
+func buildSubtree(tree []byte, s int, seed *[SeedBytes]byte, masks []byte, sk []byte) {
+	// Generate pk leaves.
+	first := s << subtreeHeight
+	for i := first; i < first+1<<subtreeHeight; i += leafChunk {
+		chacha.PrgAt(sk, seed[:], uint64(i*SkBytes))
+		for j := 0; j < leafChunk; j++ {
+			hash.Hash_n_n(tree[(T-1+i+j)*hash.Size:], sk[j*SkBytes:])
+		}
+	}
+
+	var offsetIn, offsetOut uint64
+	for i := uint(0); i < subtreeHeight; i++ {
+		offsetIn = (1 << (LogT - i)) - 1
+		offsetOut = (1 << (LogT - i - 1)) - 1
+		width := uint64(1) << (subtreeHeight - i - 1)
+		for j := uint64(s) * width; j < uint64(s+1)*width; j++ {
+			hash.Hash_2n_n_mask(tree[(offsetOut+j)*hash.Size:], tree[(offsetIn+2*j)*hash.Size:], masks[2*i*hash.Size:])
+		}
+	}
 }
 
 func Verify(pk, sig, m, masks, mHash []byte) int {
@@ -0,0 +1,132 @@
+// horst_test.go - HORST tests
+
+package horst
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/yawning/sphincs256/chacha"
+	"github.com/yawning/sphincs256/hash"
+)
+
+func TestSignVerify(t *testing.T) {
+	var seed [SeedBytes]byte
+	var masks [2 * LogT * hash.Size]byte
+	var mHash [64]byte
+	rand.Read(seed[:])
+	rand.Read(masks[:])
+	rand.Read(mHash[:])
+
+	var pk, pk2 [hash.Size]byte
+	sig := make([]byte, SigBytes)
+	Sign(sig, &pk, nil, &seed, masks[:], mHash[:], 1, nil)
+
+	if Verify(pk2[:], sig, nil, masks[:], mHash[:]) != 0 {
+		t.Fatalf("failed Verify()")
+	}
+	if pk != pk2 {
+		t.Fatalf("Verify() does not recover the public key")
+	}
+
+	// The revealed secret key elements must match the full expansion.
+	sk := make([]byte, T*SkBytes)
+	chacha.Prg(sk, seed[:])
+	sigpos := 64 * hash.Size
+	for i := 0; i < K; i++ {
+		idx := int(mHash[2*i]) + int(mHash[2*i+1])<<8
+		if !bytes.Equal(sig[sigpos:sigpos+SkBytes], sk[idx*SkBytes:(idx+1)*SkBytes]) {
+			t.Errorf("[%d]: revealed secret key element mismatch", i)
+		}
+		sigpos += SkBytes + (LogT-6)*hash.Size
+	}
+
+	mHash[0] ^= 1
+	if Verify(pk2[:], sig, nil, masks[:], mHash[:]) == 0 && pk == pk2 {
+		t.Fatalf("Verify() succeeded for the wrong message")
+	}
+}
+
+func TestSignConcurrency(t *testing.T) {
+	var seed [SeedBytes]byte
+	var masks [2 * LogT * hash.Size]byte
+	var mHash [64]byte
+	rand.Read(seed[:])
+	rand.Read(masks[:])
+	rand.Read(mHash[:])
+
+	var expectedPk [hash.Size]byte
+	expectedSig := make([]byte, SigBytes)
+	Sign(expectedSig, &expectedPk, nil, &seed, masks[:], mHash[:], 1, nil)
+
+	for _, n := range []int{0, 2, 3, nSubtrees, 2 * nSubtrees} {
+		var pk [hash.Size]byte
+		sig := make([]byte, SigBytes)
+		Sign(sig, &pk, nil, &seed, masks[:], mHash[:], n, nil)
+		if pk != expectedPk || !bytes.Equal(sig, expectedSig) {
+			t.Errorf("[%d]: output depends on concurrency", n)
+		}
+	}
+}
+
+func TestSignWipesScratch(t *testing.T) {
+	var seed [SeedBytes]byte
+	var masks [2 * LogT * hash.Size]byte
+	var mHash [64]byte
+	rand.Read(seed[:])
+	rand.Read(masks[:])
+	rand.Read(mHash[:])
+
+	var scratch Scratch
+	defer scratch.Destroy()
+	var pk [hash.Size]byte
+	sig := make([]byte, SigBytes)
+	Sign(sig, &pk, nil, &seed, masks[:], mHash[:], 1, &scratch)
+
+	if !bytes.Equal(scratch.tree[:], make([]byte, len(scratch.tree))) {
+		t.Errorf("Sign() left the tree intact")
+	}
+	if sk := scratch.skBuf.Bytes(); !bytes.Equal(sk, make([]byte, len(sk))) {
+		t.Errorf("Sign() left the secret key elements intact")
+	}
+}
+
+func BenchmarkSign(b *testing.B) {
+	var seed [SeedBytes]byte
+	var masks [2 * LogT * hash.Size]byte
+	var mHash [64]byte
+	rand.Read(seed[:])
+	rand.Read(masks[:])
+	rand.Read(mHash[:])
+
+	var pk [hash.Size]byte
+	sig := make([]byte, SigBytes)
+	scratch := new(Scratch)
+	defer scratch.Destroy()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Sign(sig, &pk, nil, &seed, masks[:], mHash[:], 1, scratch)
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	var seed [SeedBytes]byte
+	var masks [2 * LogT * hash.Size]byte
+	var mHash [64]byte
+	rand.Read(seed[:])
+	rand.Read(masks[:])
+	rand.Read(mHash[:])
+
+	var pk [hash.Size]byte
+	sig := make([]byte, SigBytes)
+	Sign(sig, &pk, nil, &seed, masks[:], mHash[:], 1, nil)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if Verify(pk[:], sig, nil, masks[:], mHash[:]) != 0 {
+			b.Fatalf("failed Verify()")
+		}
+	}
+}
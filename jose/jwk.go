@@ -0,0 +1,113 @@
+// jwk.go - JSON Web Key representation of SPHINCS-256 keys
+
+package jose
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/yawning/sphincs256"
+)
+
+const (
+	// KeyType is the JWK "kty" used for SPHINCS-256 keys.
+	KeyType = "OKP"
+
+	// Curve is the JWK "crv" used for SPHINCS-256 keys.  It is not a curve,
+	// but that is what RFC 8037 calls the sub-type of an "OKP" key.
+	Curve = "SPHINCS-256"
+)
+
+// JWK is a JSON Web Key holding a SPHINCS-256 public key, and optionally the
+// matching private key.  "x" and "d" carry the raw public and private keys,
+// and "kid" the key ID (see KeyID).
+type JWK struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	D       string `json:"d,omitempty"`
+	KeyID   string `json:"kid,omitempty"`
+}
+
+// NewPublicJWK returns a JWK for publicKey.
+func NewPublicJWK(publicKey *[sphincs256.PublicKeySize]byte) *JWK {
+	return &JWK{
+		KeyType: KeyType,
+		Curve:   Curve,
+		X:       base64.RawURLEncoding.EncodeToString(publicKey[:]),
+		KeyID:   KeyID(publicKey),
+	}
+}
+
+// NewPrivateJWK returns a JWK for privateKey, including the public key.
+func NewPrivateJWK(privateKey *[sphincs256.PrivateKeySize]byte) *JWK {
+	k := NewPublicJWK(sphincs256.PublicKeyFromPrivateKey(privateKey))
+	k.D = base64.RawURLEncoding.EncodeToString(privateKey[:])
+	return k
+}
+
+// Public returns a copy of the JWK without the private key.
+func (k *JWK) Public() *JWK {
+	pub := *k
+	pub.D = ""
+	return &pub
+}
+
+// PublicKey returns the SPHINCS-256 public key in the JWK.
+func (k *JWK) PublicKey() (*[sphincs256.PublicKeySize]byte, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("jose: invalid JWK \"x\": %v", err)
+	}
+	if len(b) != sphincs256.PublicKeySize {
+		return nil, fmt.Errorf("jose: invalid JWK \"x\" length: %d", len(b))
+	}
+
+	publicKey := new([sphincs256.PublicKeySize]byte)
+	copy(publicKey[:], b)
+	if k.KeyID != "" && k.KeyID != KeyID(publicKey) {
+		return nil, ErrKeyIDMismatch
+	}
+	return publicKey, nil
+}
+
+// PrivateKey returns the SPHINCS-256 private key in the JWK.  The public key
+// in the JWK is checked for consistency with the private key.
+func (k *JWK) PrivateKey() (*[sphincs256.PrivateKeySize]byte, error) {
+	publicKey, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if k.D == "" {
+		return nil, fmt.Errorf("jose: JWK has no private key")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(k.D)
+	if err != nil {
+		return nil, fmt.Errorf("jose: invalid JWK \"d\": %v", err)
+	}
+	if len(b) != sphincs256.PrivateKeySize {
+		return nil, fmt.Errorf("jose: invalid JWK \"d\" length: %d", len(b))
+	}
+
+	privateKey := new([sphincs256.PrivateKeySize]byte)
+	copy(privateKey[:], b)
+	if *sphincs256.PublicKeyFromPrivateKey(privateKey) != *publicKey {
+		return nil, fmt.Errorf("jose: JWK \"x\" does not match \"d\"")
+	}
+	return privateKey, nil
+}
+
+func (k *JWK) validate() error {
+	if k.KeyType != KeyType {
+		return fmt.Errorf("jose: unsupported JWK \"kty\": %q", k.KeyType)
+	}
+	if k.Curve != Curve {
+		return fmt.Errorf("jose: unsupported JWK \"crv\": %q", k.Curve)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// jwk_test.go - JSON Web Key tests
+
+package jose
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+)
+
+func TestJWK(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	b, err := json.Marshal(NewPrivateJWK(sk))
+	if err != nil {
+		t.Fatalf("failed to marshal JWK: %s", err)
+	}
+	var k JWK
+	if err = json.Unmarshal(b, &k); err != nil {
+		t.Fatalf("failed to unmarshal JWK: %s", err)
+	}
+
+	sk2, err := k.PrivateKey()
+	if err != nil {
+		t.Fatalf("failed PrivateKey(): %s", err)
+	}
+	if *sk2 != *sk {
+		t.Errorf("private key mismatch")
+	}
+	pk2, err := k.Public().PublicKey()
+	if err != nil {
+		t.Fatalf("failed PublicKey(): %s", err)
+	}
+	if *pk2 != *pk {
+		t.Errorf("public key mismatch")
+	}
+	if _, err = k.Public().PrivateKey(); err == nil {
+		t.Errorf("PrivateKey() succeeded on a public JWK")
+	}
+
+	// "x" that does not match "d".
+	otherPk, _, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	bad := *NewPublicJWK(otherPk)
+	bad.D = k.D
+	if _, err = bad.PrivateKey(); err == nil {
+		t.Errorf("PrivateKey() accepted mismatched \"x\" and \"d\"")
+	}
+
+	if k.KeyID != (*sphincs256.PublicKey)(pk).KeyID().String() {
+		t.Errorf("unexpected \"kid\": %q", k.KeyID)
+	}
+	bad = *k.Public()
+	bad.KeyID = KeyID(otherPk)
+	if _, err = bad.PublicKey(); err != ErrKeyIDMismatch {
+		t.Errorf("PublicKey() with the wrong \"kid\": %v", err)
+	}
+
+	bad = *k.Public()
+	bad.Curve = "Ed25519"
+	if _, err = bad.PublicKey(); err == nil {
+		t.Errorf("PublicKey() accepted the wrong \"crv\"")
+	}
+}
@@ -0,0 +1,204 @@
+// jws.go - JSON Web Signatures using SPHINCS-256
+
+// Package jose implements JSON Web Signature (RFC 7515) compact and JSON
+// serializations, and JSON Web Key (RFC 7517) representations, for the
+// SPHINCS-256 signature scheme.
+//
+// SPHINCS-256 does not have a registered JOSE algorithm name, so a
+// private-use "alg" is used, and only tokens that carry it are accepted.
+package jose
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yawning/sphincs256"
+)
+
+// Algorithm is the private-use JWS "alg" header value for SPHINCS-256.
+const Algorithm = "SPHINCS-256"
+
+var (
+	// ErrInvalidAlgorithm is the error returned when a JWS's "alg" header is
+	// not Algorithm.
+	ErrInvalidAlgorithm = errors.New("jose: unsupported \"alg\"")
+
+	// ErrKeyIDMismatch is the error returned when a JWS's or JWK's "kid" is
+	// not the key ID of the public key.
+	ErrKeyIDMismatch = errors.New("jose: \"kid\" does not match the public key")
+
+	// ErrVerificationFailed is the error returned when a JWS's signature is
+	// invalid.
+	ErrVerificationFailed = errors.New("jose: signature verification failed")
+)
+
+// Header is a JWS protected header.  The "alg" header is always set to
+// Algorithm, and the "kid" header to the signing key's sphincs256.KeyID (as
+// returned by KeyID) when signing.
+type Header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	Type      string `json:"typ,omitempty"`
+	Content   string `json:"cty,omitempty"`
+}
+
+// SignCompact signs payload with privateKey and returns the JWS compact
+// serialization.  hdr may be nil.
+func SignCompact(privateKey *[sphincs256.PrivateKeySize]byte, payload []byte, hdr *Header) (string, error) {
+	protected, encPayload, sig, err := sign(privateKey, payload, hdr)
+	if err != nil {
+		return "", err
+	}
+	return protected + "." + encPayload + "." + sig, nil
+}
+
+// VerifyCompact verifies a JWS compact serialization with publicKey, and
+// returns the protected header and payload if the signature is valid.
+func VerifyCompact(publicKey *[sphincs256.PublicKeySize]byte, token string) (*Header, []byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("jose: malformed compact JWS")
+	}
+	return verify(publicKey, parts[0], parts[1], parts[2])
+}
+
+// jsonSignature is a JWS JSON serialization signature entry.
+type jsonSignature struct {
+	Protected string          `json:"protected"`
+	Header    json.RawMessage `json:"header,omitempty"`
+	Signature string          `json:"signature"`
+}
+
+// jsonJWS is the union of the flattened and general JWS JSON serializations.
+type jsonJWS struct {
+	Payload string `json:"payload"`
+	jsonSignature
+	Signatures []jsonSignature `json:"signatures,omitempty"`
+}
+
+// SignJSON signs payload with privateKey and returns the flattened JWS JSON
+// serialization.  hdr may be nil.
+func SignJSON(privateKey *[sphincs256.PrivateKeySize]byte, payload []byte, hdr *Header) ([]byte, error) {
+	protected, encPayload, sig, err := sign(privateKey, payload, hdr)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&jsonJWS{
+		Payload: encPayload,
+		jsonSignature: jsonSignature{
+			Protected: protected,
+			Signature: sig,
+		},
+	})
+}
+
+// VerifyJSON verifies a flattened or general JWS JSON serialization with
+// publicKey, and returns the protected header and payload if a signature is
+// valid.  For the general serialization, the first valid signature is used.
+func VerifyJSON(publicKey *[sphincs256.PublicKeySize]byte, data []byte) (*Header, []byte, error) {
+	var jws jsonJWS
+	if err := json.Unmarshal(data, &jws); err != nil {
+		return nil, nil, fmt.Errorf("jose: malformed JSON JWS: %v", err)
+	}
+
+	sigs := jws.Signatures
+	if jws.Signature != "" {
+		if len(sigs) != 0 {
+			return nil, nil, fmt.Errorf("jose: JSON JWS is both flattened and general")
+		}
+		sigs = []jsonSignature{jws.jsonSignature}
+	}
+	if len(sigs) == 0 {
+		return nil, nil, fmt.Errorf("jose: JSON JWS has no signatures")
+	}
+
+	err := ErrVerificationFailed
+	for _, s := range sigs {
+		var hdr *Header
+		var payload []byte
+		if hdr, payload, err = verify(publicKey, s.Protected, jws.Payload, s.Signature); err == nil {
+			return hdr, payload, nil
+		}
+	}
+	return nil, nil, err
+}
+
+func sign(privateKey *[sphincs256.PrivateKeySize]byte, payload []byte, hdr *Header) (protected, encPayload, sig string, err error) {
+	h := Header{}
+	if hdr != nil {
+		h = *hdr
+	}
+	h.Algorithm = Algorithm
+	h.KeyID = KeyID(sphincs256.PublicKeyFromPrivateKey(privateKey))
+
+	rawHdr, err := json.Marshal(&h)
+	if err != nil {
+		return "", "", "", err
+	}
+	protected = base64.RawURLEncoding.EncodeToString(rawHdr)
+	encPayload = base64.RawURLEncoding.EncodeToString(payload)
+
+	s := sphincs256.Sign(privateKey, signingInput(protected, encPayload))
+	sig = base64.RawURLEncoding.EncodeToString(s[:])
+	return
+}
+
+func verify(publicKey *[sphincs256.PublicKeySize]byte, protected, encPayload, encSig string) (*Header, []byte, error) {
+	rawHdr, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return nil, nil, fmt.Errorf("jose: invalid protected header encoding: %v", err)
+	}
+
+	// Check the raw header for anything that needs to be understood, before
+	// parsing it into a Header.
+	var rawFields map[string]json.RawMessage
+	if err = json.Unmarshal(rawHdr, &rawFields); err != nil {
+		return nil, nil, fmt.Errorf("jose: malformed protected header: %v", err)
+	}
+	if _, ok := rawFields["crit"]; ok {
+		return nil, nil, fmt.Errorf("jose: unsupported \"crit\" header")
+	}
+	var hdr Header
+	if err = json.Unmarshal(rawHdr, &hdr); err != nil {
+		return nil, nil, fmt.Errorf("jose: malformed protected header: %v", err)
+	}
+	if hdr.Algorithm != Algorithm {
+		return nil, nil, ErrInvalidAlgorithm
+	}
+	if hdr.KeyID != "" && hdr.KeyID != KeyID(publicKey) {
+		return nil, nil, ErrKeyIDMismatch
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("jose: invalid payload encoding: %v", err)
+	}
+	rawSig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("jose: invalid signature encoding: %v", err)
+	}
+	if len(rawSig) != sphincs256.SignatureSize {
+		return nil, nil, ErrVerificationFailed
+	}
+
+	var sig [sphincs256.SignatureSize]byte
+	copy(sig[:], rawSig)
+	if !sphincs256.Verify(publicKey, signingInput(protected, encPayload), &sig) {
+		return nil, nil, ErrVerificationFailed
+	}
+	return &hdr, payload, nil
+}
+
+// KeyID returns the "kid" of publicKey, the string form of its
+// sphincs256.KeyID.
+func KeyID(publicKey *[sphincs256.PublicKeySize]byte) string {
+	return (*sphincs256.PublicKey)(publicKey).KeyID().String()
+}
+
+func signingInput(protected, encPayload string) []byte {
+	return []byte(protected + "." + encPayload)
+}
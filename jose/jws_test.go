@@ -0,0 +1,129 @@
+// jws_test.go - JSON Web Signature tests
+
+package jose
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+)
+
+const testPayload = `{"iss":"miskatonic.edu","sub":"wilmarth","aud":"arkham"}`
+
+func TestCompact(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	token, err := SignCompact(sk, []byte(testPayload), &Header{KeyID: "test", Type: "JWT"})
+	if err != nil {
+		t.Fatalf("failed SignCompact(): %s", err)
+	}
+
+	hdr, payload, err := VerifyCompact(pk, token)
+	if err != nil {
+		t.Fatalf("failed VerifyCompact(): %s", err)
+	}
+	if hdr.Algorithm != Algorithm || hdr.KeyID != KeyID(pk) || hdr.Type != "JWT" {
+		t.Errorf("header mismatch: %+v", hdr)
+	}
+	if !bytes.Equal(payload, []byte(testPayload)) {
+		t.Errorf("payload mismatch")
+	}
+
+	parts := strings.Split(token, ".")
+
+	// Tampered payload.
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(testPayload+" ")) + "." + parts[2]
+	if _, _, err = VerifyCompact(pk, tampered); err != ErrVerificationFailed {
+		t.Errorf("VerifyCompact() with tampered payload: %v", err)
+	}
+
+	// Mismatched "alg", with and without a signature.
+	for _, alg := range []string{"EdDSA", "none", "sphincs-256"} {
+		forged := encodeHeader(t, map[string]string{"alg": alg})
+		for _, sig := range []string{parts[2], ""} {
+			if _, _, err = VerifyCompact(pk, forged+"."+parts[1]+"."+sig); err != ErrInvalidAlgorithm {
+				t.Errorf("VerifyCompact() with alg %q: %v", alg, err)
+			}
+		}
+	}
+
+	// Unsupported critical extensions.
+	crit := encodeHeader(t, map[string]interface{}{"alg": Algorithm, "crit": []string{"exp"}, "exp": 0})
+	if _, _, err = VerifyCompact(pk, crit+"."+parts[1]+"."+parts[2]); err == nil {
+		t.Errorf("VerifyCompact() accepted a \"crit\" header")
+	}
+
+	// Another key.
+	otherPk, _, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	if _, _, err = VerifyCompact(otherPk, token); err != ErrKeyIDMismatch {
+		t.Errorf("VerifyCompact() with another key: %v", err)
+	}
+
+	if _, _, err = VerifyCompact(pk, parts[0]+"."+parts[1]); err == nil {
+		t.Errorf("VerifyCompact() accepted a truncated token")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	flattened, err := SignJSON(sk, []byte(testPayload), nil)
+	if err != nil {
+		t.Fatalf("failed SignJSON(): %s", err)
+	}
+	_, payload, err := VerifyJSON(pk, flattened)
+	if err != nil {
+		t.Fatalf("failed VerifyJSON(): %s", err)
+	}
+	if !bytes.Equal(payload, []byte(testPayload)) {
+		t.Errorf("payload mismatch")
+	}
+
+	// Convert to the general serialization, with a bogus signature first.
+	var jws jsonJWS
+	if err = json.Unmarshal(flattened, &jws); err != nil {
+		t.Fatalf("failed to parse flattened JWS: %s", err)
+	}
+	general, _ := json.Marshal(map[string]interface{}{
+		"payload": jws.Payload,
+		"signatures": []jsonSignature{
+			{Protected: encodeHeader(t, map[string]string{"alg": "ES256"}), Signature: "AAAA"},
+			jws.jsonSignature,
+		},
+	})
+	if _, payload, err = VerifyJSON(pk, general); err != nil {
+		t.Fatalf("failed VerifyJSON() (general): %s", err)
+	}
+	if !bytes.Equal(payload, []byte(testPayload)) {
+		t.Errorf("payload mismatch (general)")
+	}
+
+	// Mismatched "alg".
+	jws.Protected = encodeHeader(t, map[string]string{"alg": "HS256"})
+	forged, _ := json.Marshal(&jws)
+	if _, _, err = VerifyJSON(pk, forged); err != ErrInvalidAlgorithm {
+		t.Errorf("VerifyJSON() with mismatched alg: %v", err)
+	}
+}
+
+func encodeHeader(t *testing.T, hdr interface{}) string {
+	b, err := json.Marshal(hdr)
+	if err != nil {
+		t.Fatalf("failed to marshal header: %s", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
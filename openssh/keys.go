@@ -0,0 +1,196 @@
+// keys.go - OpenSSH public keys, signatures and signer
+
+// Package openssh implements the OpenSSH public key, "openssh-key-v1" private
+// key and signature formats for the SPHINCS-256 signature scheme.
+//
+// The package is self contained, and does not depend on
+// golang.org/x/crypto/ssh.  The PublicKey, Signature and Signer types are
+// similar to, but do not implement, that package's interfaces, as doing so
+// requires its concrete types.  The sshadapter subpackage adapts them to
+// those interfaces.
+package openssh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/yawning/sphincs256"
+)
+
+// KeyAlgo is the SSH public key and signature algorithm name for
+// SPHINCS-256.
+const KeyAlgo = "sphincs256-blake@schwanenlied.me"
+
+// ErrVerificationFailed is the error returned when a signature is invalid.
+var ErrVerificationFailed = errors.New("openssh: signature verification failed")
+
+// PublicKey is a SPHINCS-256 SSH public key.
+type PublicKey struct {
+	key [sphincs256.PublicKeySize]byte
+}
+
+// NewPublicKey returns a PublicKey for publicKey.
+func NewPublicKey(publicKey *[sphincs256.PublicKeySize]byte) *PublicKey {
+	k := new(PublicKey)
+	copy(k.key[:], publicKey[:])
+	return k
+}
+
+// Key returns the raw SPHINCS-256 public key.
+func (k *PublicKey) Key() *[sphincs256.PublicKeySize]byte {
+	pk := k.key
+	return &pk
+}
+
+// KeyID returns the key's sphincs256.KeyID.
+func (k *PublicKey) KeyID() sphincs256.KeyID {
+	return (*sphincs256.PublicKey)(&k.key).KeyID()
+}
+
+// Type returns the key's algorithm name.
+func (k *PublicKey) Type() string {
+	return KeyAlgo
+}
+
+// Marshal returns the serialized key in SSH wire format.
+func (k *PublicKey) Marshal() []byte {
+	b := make([]byte, 0, 4+len(KeyAlgo)+4+sphincs256.PublicKeySize)
+	b = appendString(b, []byte(KeyAlgo))
+	return appendString(b, k.key[:])
+}
+
+// Verify checks that sig is a valid signature over data by the key.
+func (k *PublicKey) Verify(data []byte, sig *Signature) error {
+	if sig.Format != KeyAlgo {
+		return fmt.Errorf("openssh: unexpected signature format: %q", sig.Format)
+	}
+	if len(sig.Blob) != sphincs256.SignatureSize {
+		return ErrVerificationFailed
+	}
+
+	var s [sphincs256.SignatureSize]byte
+	copy(s[:], sig.Blob)
+	if !sphincs256.Verify(&k.key, data, &s) {
+		return ErrVerificationFailed
+	}
+	return nil
+}
+
+// ParsePublicKey parses a SSH wire format public key.
+func ParsePublicKey(in []byte) (*PublicKey, error) {
+	r := &reader{b: in}
+	algo := r.string()
+	pk := r.string()
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+	if string(algo) != KeyAlgo {
+		return nil, fmt.Errorf("openssh: unsupported key type: %q", algo)
+	}
+	if len(pk) != sphincs256.PublicKeySize {
+		return nil, fmt.Errorf("openssh: invalid public key length: %d", len(pk))
+	}
+
+	k := new(PublicKey)
+	copy(k.key[:], pk)
+	return k, nil
+}
+
+// MarshalAuthorizedKey serializes a key as a line suitable for inclusion in
+// an OpenSSH authorized_keys or .pub file.  The line is newline terminated.
+func MarshalAuthorizedKey(k *PublicKey, comment string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(KeyAlgo)
+	buf.WriteByte(' ')
+	buf.WriteString(base64.StdEncoding.EncodeToString(k.Marshal()))
+	if comment != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(comment)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// ParseAuthorizedKey parses a single OpenSSH public key line, as produced by
+// MarshalAuthorizedKey.  Key options are not supported.
+func ParseAuthorizedKey(in []byte) (*PublicKey, string, error) {
+	fields := bytes.Fields(in)
+	if len(fields) < 2 {
+		return nil, "", fmt.Errorf("openssh: malformed public key line")
+	}
+	if string(fields[0]) != KeyAlgo {
+		return nil, "", fmt.Errorf("openssh: unsupported key type: %q", fields[0])
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(string(fields[1]))
+	if err != nil {
+		return nil, "", fmt.Errorf("openssh: invalid public key encoding: %v", err)
+	}
+	k, err := ParsePublicKey(blob)
+	if err != nil {
+		return nil, "", err
+	}
+	return k, string(bytes.Join(fields[2:], []byte{' '})), nil
+}
+
+// Signature is a SSH signature.
+type Signature struct {
+	Format string
+	Blob   []byte
+	Rest   []byte
+}
+
+// Marshal returns the serialized signature in SSH wire format.
+func (s *Signature) Marshal() []byte {
+	b := make([]byte, 0, 4+len(s.Format)+4+len(s.Blob)+len(s.Rest))
+	b = appendString(b, []byte(s.Format))
+	b = appendString(b, s.Blob)
+	return append(b, s.Rest...)
+}
+
+// ParseSignature parses a SSH wire format signature.
+func ParseSignature(in []byte) (*Signature, error) {
+	r := &reader{b: in}
+	format := r.string()
+	blob := r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	s := &Signature{
+		Format: string(format),
+		Blob:   append([]byte{}, blob...),
+	}
+	if len(r.b) != 0 {
+		s.Rest = append([]byte{}, r.b...)
+	}
+	return s, nil
+}
+
+// Signer signs data with a SPHINCS-256 private key.
+type Signer struct {
+	privateKey [sphincs256.PrivateKeySize]byte
+	publicKey  *PublicKey
+}
+
+// NewSigner returns a Signer for privateKey.
+func NewSigner(privateKey *[sphincs256.PrivateKeySize]byte) *Signer {
+	s := &Signer{publicKey: NewPublicKey(sphincs256.PublicKeyFromPrivateKey(privateKey))}
+	copy(s.privateKey[:], privateKey[:])
+	return s
+}
+
+// PublicKey returns the public key corresponding to the Signer's private key.
+func (s *Signer) PublicKey() *PublicKey {
+	return s.publicKey
+}
+
+// Sign returns a signature over data.  SPHINCS-256 signing is deterministic,
+// so rand is unused.
+func (s *Signer) Sign(rand io.Reader, data []byte) (*Signature, error) {
+	sig := sphincs256.Sign(&s.privateKey, data)
+	return &Signature{Format: KeyAlgo, Blob: sig[:]}, nil
+}
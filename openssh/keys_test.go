@@ -0,0 +1,89 @@
+// keys_test.go - OpenSSH public key, signature and signer tests
+
+package openssh
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+)
+
+func TestAuthorizedKey(t *testing.T) {
+	pk, _, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	const comment = "wilmarth@akeley farm"
+	line := MarshalAuthorizedKey(NewPublicKey(pk), comment)
+	if !strings.HasPrefix(string(line), KeyAlgo+" ") {
+		t.Fatalf("public key line has the wrong prefix")
+	}
+
+	k, c, err := ParseAuthorizedKey(line)
+	if err != nil {
+		t.Fatalf("failed ParseAuthorizedKey(): %s", err)
+	}
+	if *k.Key() != *pk || c != comment {
+		t.Errorf("public key line round trip mismatch")
+	}
+	if k.KeyID() != (*sphincs256.PublicKey)(pk).KeyID() {
+		t.Errorf("key ID mismatch")
+	}
+
+	// The wire format is string(keytype) || string(pk).
+	blob := k.Marshal()
+	r := &reader{b: blob}
+	if string(r.string()) != KeyAlgo || !bytes.Equal(r.string(), pk[:]) || r.finish() != nil {
+		t.Errorf("unexpected public key wire format")
+	}
+
+	if _, _, err = ParseAuthorizedKey([]byte("ssh-ed25519 " + string(line[len(KeyAlgo)+1:]))); err == nil {
+		t.Errorf("ParseAuthorizedKey() accepted the wrong key type")
+	}
+	if _, err = ParsePublicKey(blob[:len(blob)-1]); err == nil {
+		t.Errorf("ParsePublicKey() accepted a truncated key")
+	}
+}
+
+func TestSigner(t *testing.T) {
+	const data = "SSH session identifier and friends"
+
+	_, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	signer := NewSigner(sk)
+	sig, err := signer.Sign(rand.Reader, []byte(data))
+	if err != nil {
+		t.Fatalf("failed Sign(): %s", err)
+	}
+
+	// Round trip the public key and signature through the wire format.
+	pub, err := ParsePublicKey(signer.PublicKey().Marshal())
+	if err != nil {
+		t.Fatalf("failed ParsePublicKey(): %s", err)
+	}
+	sig2, err := ParseSignature(sig.Marshal())
+	if err != nil {
+		t.Fatalf("failed ParseSignature(): %s", err)
+	}
+	if sig2.Format != KeyAlgo || !bytes.Equal(sig2.Blob, sig.Blob) || sig2.Rest != nil {
+		t.Errorf("signature round trip mismatch")
+	}
+
+	if err = pub.Verify([]byte(data), sig2); err != nil {
+		t.Errorf("failed Verify(): %s", err)
+	}
+	if err = pub.Verify([]byte(data[1:]), sig2); err != ErrVerificationFailed {
+		t.Errorf("Verify() with the wrong data: %v", err)
+	}
+	sig2.Format = "ssh-ed25519"
+	if err = pub.Verify([]byte(data), sig2); err == nil {
+		t.Errorf("Verify() accepted the wrong signature format")
+	}
+}
@@ -0,0 +1,125 @@
+// privkey.go - OpenSSH "openssh-key-v1" private keys
+
+package openssh
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/yawning/sphincs256"
+)
+
+const (
+	privateKeyMagic   = "openssh-key-v1\x00"
+	privateKeyPEMType = "OPENSSH PRIVATE KEY"
+
+	// Unencrypted private sections are padded to a multiple of 8 bytes.
+	privateKeyBlockSize = 8
+)
+
+// MarshalPrivateKey serializes privateKey and comment as an unencrypted PEM
+// armored "openssh-key-v1" private key, using randomness from rand for the
+// check integer.
+func MarshalPrivateKey(rand io.Reader, privateKey *[sphincs256.PrivateKeySize]byte, comment string) ([]byte, error) {
+	var check [4]byte
+	if _, err := io.ReadFull(rand, check[:]); err != nil {
+		return nil, err
+	}
+	pk := sphincs256.PublicKeyFromPrivateKey(privateKey)
+
+	// The private section: checkint, checkint, keytype, pk, sk, comment,
+	// padding.
+	priv := append([]byte{}, check[:]...)
+	priv = append(priv, check[:]...)
+	priv = appendString(priv, []byte(KeyAlgo))
+	priv = appendString(priv, pk[:])
+	priv = appendString(priv, privateKey[:])
+	priv = appendString(priv, []byte(comment))
+	for i := byte(1); len(priv)%privateKeyBlockSize != 0; i++ {
+		priv = append(priv, i)
+	}
+
+	b := []byte(privateKeyMagic)
+	b = appendString(b, []byte("none")) // ciphername
+	b = appendString(b, []byte("none")) // kdfname
+	b = appendString(b, nil)            // kdfoptions
+	b = appendUint32(b, 1)              // number of keys
+	b = appendString(b, NewPublicKey(pk).Marshal())
+	b = appendString(b, priv)
+
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMType, Bytes: b}), nil
+}
+
+// ParsePrivateKey parses an unencrypted PEM armored "openssh-key-v1" private
+// key, and returns the private key and comment.
+func ParsePrivateKey(pemBytes []byte) (*[sphincs256.PrivateKeySize]byte, string, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, "", fmt.Errorf("openssh: no PEM data found")
+	}
+	if block.Type != privateKeyPEMType {
+		return nil, "", fmt.Errorf("openssh: unexpected PEM type: %q", block.Type)
+	}
+	if !bytes.HasPrefix(block.Bytes, []byte(privateKeyMagic)) {
+		return nil, "", fmt.Errorf("openssh: invalid private key magic")
+	}
+
+	r := &reader{b: block.Bytes[len(privateKeyMagic):]}
+	cipherName := r.string()
+	kdfName := r.string()
+	r.string() // kdfoptions
+	nKeys := r.uint32()
+	pubBlob := r.string()
+	priv := r.string()
+	if err := r.finish(); err != nil {
+		return nil, "", err
+	}
+	if string(cipherName) != "none" || string(kdfName) != "none" {
+		return nil, "", fmt.Errorf("openssh: encrypted private keys are not supported")
+	}
+	if nKeys != 1 {
+		return nil, "", fmt.Errorf("openssh: unsupported number of keys: %d", nKeys)
+	}
+	if len(priv)%privateKeyBlockSize != 0 {
+		return nil, "", fmt.Errorf("openssh: invalid private section length")
+	}
+	pub, err := ParsePublicKey(pubBlob)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r = &reader{b: priv}
+	check1 := r.uint32()
+	check2 := r.uint32()
+	keyType := r.string()
+	pk := r.string()
+	sk := r.string()
+	comment := r.string()
+	if r.err != nil {
+		return nil, "", r.err
+	}
+	if check1 != check2 {
+		return nil, "", fmt.Errorf("openssh: check integer mismatch")
+	}
+	if string(keyType) != KeyAlgo {
+		return nil, "", fmt.Errorf("openssh: unsupported key type: %q", keyType)
+	}
+	for i, b := range r.b {
+		if b != byte(i+1) {
+			return nil, "", fmt.Errorf("openssh: invalid padding")
+		}
+	}
+	if len(sk) != sphincs256.PrivateKeySize {
+		return nil, "", fmt.Errorf("openssh: invalid private key length: %d", len(sk))
+	}
+
+	privateKey := new([sphincs256.PrivateKeySize]byte)
+	copy(privateKey[:], sk)
+	derived := sphincs256.PublicKeyFromPrivateKey(privateKey)
+	if !bytes.Equal(derived[:], pk) || *derived != pub.key {
+		return nil, "", fmt.Errorf("openssh: public key does not match private key")
+	}
+	return privateKey, string(comment), nil
+}
@@ -0,0 +1,70 @@
+// sshadapter.go - golang.org/x/crypto/ssh adapter
+
+// Package sshadapter adapts the openssh package's SPHINCS-256 public keys and
+// signer to the golang.org/x/crypto/ssh PublicKey and Signer interfaces, so
+// that they can be used for example to sign certificates with
+// ssh.Certificate.SignCert.
+//
+// The ssh package does not know the SPHINCS-256 key type, and so can not
+// parse SPHINCS-256 keys, or certificates signed by them, by itself.  Such
+// keys must be parsed with the openssh package and wrapped with NewPublicKey.
+package sshadapter
+
+import (
+	"io"
+
+	"github.com/yawning/sphincs256/openssh"
+	"golang.org/x/crypto/ssh"
+)
+
+type publicKey struct {
+	k *openssh.PublicKey
+}
+
+// NewPublicKey returns an ssh.PublicKey for k.
+func NewPublicKey(k *openssh.PublicKey) ssh.PublicKey {
+	return &publicKey{k}
+}
+
+func (k *publicKey) Type() string {
+	return k.k.Type()
+}
+
+func (k *publicKey) Marshal() []byte {
+	return k.k.Marshal()
+}
+
+func (k *publicKey) Verify(data []byte, sig *ssh.Signature) error {
+	return k.k.Verify(data, &openssh.Signature{Format: sig.Format, Blob: sig.Blob, Rest: sig.Rest})
+}
+
+// ParsePublicKey parses a SSH wire format SPHINCS-256 public key and returns
+// it as an ssh.PublicKey.
+func ParsePublicKey(in []byte) (ssh.PublicKey, error) {
+	k, err := openssh.ParsePublicKey(in)
+	if err != nil {
+		return nil, err
+	}
+	return NewPublicKey(k), nil
+}
+
+type signer struct {
+	s *openssh.Signer
+}
+
+// NewSigner returns an ssh.Signer for s.
+func NewSigner(s *openssh.Signer) ssh.Signer {
+	return &signer{s}
+}
+
+func (s *signer) PublicKey() ssh.PublicKey {
+	return NewPublicKey(s.s.PublicKey())
+}
+
+func (s *signer) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	sig, err := s.s.Sign(rand, data)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.Signature{Format: sig.Format, Blob: sig.Blob, Rest: sig.Rest}, nil
+}
@@ -0,0 +1,88 @@
+// sshadapter_test.go - golang.org/x/crypto/ssh adapter tests
+
+package sshadapter
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+	"github.com/yawning/sphincs256/openssh"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSigner(t *testing.T) {
+	const data = "SSH session identifier and friends"
+
+	_, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	signer := NewSigner(openssh.NewSigner(sk))
+	sig, err := signer.Sign(rand.Reader, []byte(data))
+	if err != nil {
+		t.Fatalf("failed Sign(): %s", err)
+	}
+	if sig.Format != openssh.KeyAlgo {
+		t.Errorf("unexpected signature format: %q", sig.Format)
+	}
+
+	pub, err := ParsePublicKey(signer.PublicKey().Marshal())
+	if err != nil {
+		t.Fatalf("failed ParsePublicKey(): %s", err)
+	}
+	if pub.Type() != openssh.KeyAlgo || !bytes.Equal(pub.Marshal(), signer.PublicKey().Marshal()) {
+		t.Errorf("public key round trip mismatch")
+	}
+	if err = pub.Verify([]byte(data), sig); err != nil {
+		t.Errorf("failed Verify(): %s", err)
+	}
+	if err = pub.Verify([]byte(data[1:]), sig); err == nil {
+		t.Errorf("Verify() accepted the wrong data")
+	}
+}
+
+func TestSignCert(t *testing.T) {
+	_, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	authority := NewSigner(openssh.NewSigner(sk))
+
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed ed25519.GenerateKey(): %s", err)
+	}
+	key, err := ssh.NewPublicKey(edPub)
+	if err != nil {
+		t.Fatalf("failed ssh.NewPublicKey(): %s", err)
+	}
+
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.UserCert,
+		KeyId:           "lab user",
+		ValidPrincipals: []string{"herbert"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err = cert.SignCert(rand.Reader, authority); err != nil {
+		t.Fatalf("failed SignCert(): %s", err)
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), authority.PublicKey().Marshal())
+		},
+	}
+	if err = checker.CheckCert("herbert", cert); err != nil {
+		t.Fatalf("failed CheckCert(): %s", err)
+	}
+
+	cert.KeyId = "someone else"
+	if err = checker.CheckCert("herbert", cert); err == nil {
+		t.Fatalf("CheckCert() accepted a modified certificate")
+	}
+}
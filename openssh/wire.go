@@ -0,0 +1,62 @@
+// wire.go - SSH wire format (RFC 4251) primitives
+
+package openssh
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+func appendUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
+
+func appendString(b, s []byte) []byte {
+	b = appendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// reader consumes SSH wire format values from a byte slice, keeping track of
+// the first error encountered.
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.b) < 4 {
+		r.err = fmt.Errorf("openssh: truncated data")
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *reader) string() []byte {
+	n := r.uint32()
+	if r.err != nil {
+		return nil
+	}
+	if uint64(n) > uint64(len(r.b)) {
+		r.err = fmt.Errorf("openssh: truncated data")
+		return nil
+	}
+	s := r.b[:n]
+	r.b = r.b[n:]
+	return s
+}
+
+// finish returns the first error encountered, or an error if there is
+// unconsumed data.
+func (r *reader) finish() error {
+	if r.err == nil && len(r.b) != 0 {
+		r.err = fmt.Errorf("openssh: trailing data")
+	}
+	return r.err
+}
@@ -0,0 +1,72 @@
+// prehash.go - SPHINCS-256 pre-hashed signatures (HashSPHINCS-256)
+
+package sphincs256
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedHash is the error returned when a pre-hashed signature is
+// requested with an unsupported digest algorithm.
+var ErrUnsupportedHash = errors.New("sphincs256: unsupported pre-hash digest algorithm")
+
+// prehashOIDs are the DER encoded object identifiers of the supported
+// pre-hash digest algorithms.
+var prehashOIDs = map[crypto.Hash][]byte{
+	crypto.SHA256:      mustMarshalOID(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}),
+	crypto.SHA512:      mustMarshalOID(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}),
+	crypto.BLAKE2b_512: mustMarshalOID(asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 1722, 12, 2, 1, 16}),
+}
+
+func mustMarshalOID(oid asn1.ObjectIdentifier) []byte {
+	b, err := asn1.Marshal(oid)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// SignPrehashed signs digest, the output of the digest algorithm h over the
+// message, with privateKey and returns the signature (HashSPHINCS-256).  The
+// supported digest algorithms are crypto.SHA256, crypto.SHA512 and
+// crypto.BLAKE2b_512.
+//
+// The message signed is the DER encoded object identifier of h followed by
+// the digest, in a separate signature mode that is mixed into the hash
+// inputs ahead of the message.  Pre-hashed signatures thus only verify with
+// VerifyPrehashed and the same digest algorithm, and no pure signature, over
+// any message, verifies as a pre-hashed one or vice versa.
+func SignPrehashed(privateKey *[PrivateKeySize]byte, h crypto.Hash, digest []byte) (*[SignatureSize]byte, error) {
+	oid, err := prehashOID(h, digest)
+	if err != nil {
+		return nil, err
+	}
+	return sign(privateKey, modePrehash, oid, digest, nil, nil), nil
+}
+
+// VerifyPrehashed takes a public key, the digest algorithm, digest and
+// signature and returns true if the signature is a valid pre-hashed
+// signature, as returned by SignPrehashed.
+func VerifyPrehashed(publicKey *[PublicKeySize]byte, h crypto.Hash, digest []byte, signature *[SignatureSize]byte) bool {
+	oid, err := prehashOID(h, digest)
+	if err != nil {
+		return false
+	}
+	return verify(publicKey, modePrehash, oid, digest, signature, nil)
+}
+
+// prehashOID returns the DER encoded object identifier of h, after checking
+// that digest is the right size.
+func prehashOID(h crypto.Hash, digest []byte) ([]byte, error) {
+	oid, ok := prehashOIDs[h]
+	if !ok {
+		return nil, ErrUnsupportedHash
+	}
+	if len(digest) != h.Size() {
+		return nil, fmt.Errorf("sphincs256: invalid %v digest size %d", h, len(digest))
+	}
+	return oid, nil
+}
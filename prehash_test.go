@@ -0,0 +1,149 @@
+// prehash_test.go - SPHINCS-256 pre-hashed signature tests
+
+package sphincs256
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPrehashOIDs(t *testing.T) {
+	for h, expected := range map[crypto.Hash]string{
+		crypto.SHA256:      "0609608648016503040201",
+		crypto.SHA512:      "0609608648016503040203",
+		crypto.BLAKE2b_512: "060b2b060104018d3a0c020110",
+	} {
+		if oid := hex.EncodeToString(prehashOIDs[h]); oid != expected {
+			t.Errorf("%v: OID %s, expected %s", h, oid, expected)
+		}
+	}
+}
+
+func TestSignPrehashed(t *testing.T) {
+	const msg = "The Shadow over Innsmouth"
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	sha256Digest := sha256.Sum256([]byte(msg))
+	sha512Digest := sha512.Sum512([]byte(msg))
+	for _, v := range []struct {
+		h      crypto.Hash
+		digest []byte
+	}{
+		{crypto.SHA256, sha256Digest[:]},
+		{crypto.SHA512, sha512Digest[:]},
+		{crypto.BLAKE2b_512, sha512Digest[:]}, // Any 64 byte digest will do.
+	} {
+		sig, err := SignPrehashed(sk, v.h, v.digest)
+		if err != nil {
+			t.Fatalf("%v: failed SignPrehashed(): %s", v.h, err)
+		}
+		if !VerifyPrehashed(pk, v.h, v.digest, sig) {
+			t.Fatalf("%v: failed VerifyPrehashed()", v.h)
+		}
+
+		// Pre-hashed signatures must not verify as pure signatures over the
+		// digest or the message, or with another digest algorithm.
+		if Verify(pk, v.digest, sig) || Verify(pk, []byte(msg), sig) {
+			t.Fatalf("%v: pre-hashed signature verified as pure", v.h)
+		}
+		for _, h := range []crypto.Hash{crypto.SHA512, crypto.BLAKE2b_512} {
+			if h != v.h && VerifyPrehashed(pk, h, v.digest, sig) {
+				t.Fatalf("%v: signature verified with %v", v.h, h)
+			}
+		}
+	}
+
+	// Pure signatures must not verify as pre-hashed signatures, even over
+	// the message that a pre-hashed signature signs, and vice versa.
+	sig := Sign(sk, sha256Digest[:])
+	if VerifyPrehashed(pk, crypto.SHA256, sha256Digest[:], sig) {
+		t.Fatalf("pure signature verified as pre-hashed")
+	}
+	encoded := append(append([]byte{}, prehashOIDs[crypto.SHA256]...), sha256Digest[:]...)
+	if VerifyPrehashed(pk, crypto.SHA256, sha256Digest[:], Sign(sk, encoded)) {
+		t.Fatalf("pure signature over the encoded digest verified as pre-hashed")
+	}
+	if sig, err = SignPrehashed(sk, crypto.SHA256, sha256Digest[:]); err != nil {
+		t.Fatalf("failed SignPrehashed(): %s", err)
+	}
+	if Verify(pk, encoded, sig) {
+		t.Fatalf("pre-hashed signature verified as pure over the encoded digest")
+	}
+
+	// R is attacker controlled, so tweaking it must not move a signature
+	// between modes either.
+	pureSig := Sign(sk, encoded)
+	for _, delta := range []byte{modePrehash, modeContext, modePrehash ^ modeContext} {
+		if VerifyPrehashed(pk, crypto.SHA256, sha256Digest[:], tweakR(pureSig, delta)) {
+			t.Fatalf("pure signature with R ^ %d verified as pre-hashed", delta)
+		}
+		if Verify(pk, encoded, tweakR(sig, delta)) {
+			t.Fatalf("pre-hashed signature with R ^ %d verified as pure", delta)
+		}
+	}
+
+	if _, err = SignPrehashed(sk, crypto.SHA1, make([]byte, crypto.SHA1.Size())); err != ErrUnsupportedHash {
+		t.Fatalf("SignPrehashed() with SHA-1 returned: %v", err)
+	}
+	if _, err = SignPrehashed(sk, crypto.SHA256, sha512Digest[:]); err == nil {
+		t.Fatalf("SignPrehashed() accepted a truncated digest")
+	}
+}
+
+// tweakR returns a copy of sig with the first byte of R XORed with delta.
+func tweakR(sig *[SignatureSize]byte, delta byte) *[SignatureSize]byte {
+	tweaked := *sig
+	tweaked[0] ^= delta
+	return &tweaked
+}
+
+func TestSigner(t *testing.T) {
+	const msg = "At the Mountains of Madness"
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	var signer crypto.Signer = NewSigner(sk)
+	if *signer.Public().(*PublicKey) != *pk {
+		t.Fatalf("Public() does not match GenerateKey()")
+	}
+
+	var b []byte
+	for _, opts := range []crypto.SignerOpts{nil, (*Options)(nil), &Options{}} {
+		b, err = signer.Sign(rand.Reader, []byte(msg), opts)
+		if err != nil {
+			t.Fatalf("failed pure Sign(): %s", err)
+		}
+		if !bytes.Equal(b, Sign(sk, []byte(msg))[:]) {
+			t.Fatalf("pure Sign() does not match Sign()")
+		}
+	}
+
+	digest := sha512.Sum512([]byte(msg))
+	for _, opts := range []crypto.SignerOpts{crypto.SHA512, &Options{Hash: crypto.SHA512}} {
+		b, err = signer.Sign(rand.Reader, digest[:], opts)
+		if err != nil {
+			t.Fatalf("failed pre-hashed Sign(): %s", err)
+		}
+		var sig [SignatureSize]byte
+		copy(sig[:], b)
+		if len(b) != SignatureSize || !VerifyPrehashed(pk, crypto.SHA512, digest[:], &sig) {
+			t.Fatalf("failed VerifyPrehashed()")
+		}
+	}
+
+	if _, err = signer.Sign(rand.Reader, digest[:32], crypto.SHA1); err != ErrUnsupportedHash {
+		t.Fatalf("Sign() with SHA-1 returned: %v", err)
+	}
+}
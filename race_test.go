@@ -0,0 +1,7 @@
+// race_test.go - Race detector build flag
+
+// +build race
+
+package sphincs256
+
+const raceEnabled = true
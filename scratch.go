@@ -0,0 +1,59 @@
+// scratch.go - SPHINCS-256 reusable signing scratch space
+
+package sphincs256
+
+import (
+	stdhash "hash"
+
+	"github.com/yawning/sphincs256/horst"
+	"github.com/yawning/sphincs256/securemem"
+
+	"github.com/dchest/blake512"
+)
+
+// Scratch is reusable working memory for SignTo.  It is large (a little over
+// 4 MiB, mostly for the HORST tree), so callers that sign frequently should
+// keep them around, for example in a sync.Pool, rather than creating one per
+// signature.  The zero value is ready for use, and the memory is allocated
+// on first use.  A Scratch must not be used concurrently.
+//
+// Secret intermediate values are wiped after each signature, except for the
+// internal state of the BLAKE-512 digest, which can not be wiped.
+type Scratch struct {
+	tskBuf *securemem.Buffer
+	h      stdhash.Hash
+	rnd    [blake512.Size]byte
+	mH     [blake512.Size]byte
+
+	tree  treeScratch
+	horst horst.Scratch
+}
+
+// NewScratch returns a new Scratch.
+func NewScratch() *Scratch {
+	s := new(Scratch)
+	s.init()
+	return s
+}
+
+// init allocates the parts of the Scratch that the zero value lacks.
+func (s *Scratch) init() {
+	if s.tskBuf == nil {
+		s.tskBuf = securemem.New(PrivateKeySize)
+	}
+	if s.h == nil {
+		s.h = blake512.New()
+	}
+}
+
+// Destroy wipes and releases the secure memory held by the Scratch.  The
+// Scratch may be used again afterwards, in which case the memory is
+// allocated again.
+func (s *Scratch) Destroy() {
+	if s.tskBuf != nil {
+		s.tskBuf.Destroy()
+		s.tskBuf = nil
+	}
+	s.tree.wots.Destroy()
+	s.horst.Destroy()
+}
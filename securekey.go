@@ -0,0 +1,77 @@
+// securekey.go - SPHINCS-256 private keys in secure memory
+
+package sphincs256
+
+import (
+	"io"
+	"runtime"
+
+	"github.com/yawning/sphincs256/securemem"
+)
+
+// SecurePrivateKey is a SPHINCS-256 private key held in secure memory (see
+// the securemem package), that is wiped when it is destroyed.
+//
+// The private key is only accessed via methods, which keep the
+// SecurePrivateKey reachable while it is in use.  Handing out a pointer to the
+// underlying memory would allow the garbage collector to release it via the
+// finalizer while it is still being used.
+type SecurePrivateKey struct {
+	buf *securemem.Buffer
+}
+
+// GenerateSecureKey generates a public/private key pair using randomness from
+// rand, with the private key held in secure memory.
+func GenerateSecureKey(rand io.Reader) (publicKey *[PublicKeySize]byte, privateKey *SecurePrivateKey, err error) {
+	privateKey = &SecurePrivateKey{buf: securemem.New(PrivateKeySize)}
+	if _, err = io.ReadFull(rand, privateKey.key()[:]); err != nil {
+		privateKey.Destroy()
+		return nil, nil, err
+	}
+	return privateKey.PublicKey(), privateKey, nil
+}
+
+// NewSecurePrivateKey copies privateKey into secure memory.  The caller is
+// responsible for wiping the original.
+func NewSecurePrivateKey(privateKey *[PrivateKeySize]byte) *SecurePrivateKey {
+	k := &SecurePrivateKey{buf: securemem.New(PrivateKeySize)}
+	copy(k.key()[:], privateKey[:])
+	runtime.KeepAlive(k)
+	return k
+}
+
+// PublicKey returns the public key corresponding to the private key.
+func (k *SecurePrivateKey) PublicKey() *[PublicKeySize]byte {
+	defer runtime.KeepAlive(k)
+	return PublicKeyFromPrivateKey(k.key())
+}
+
+// Sign signs the message with the private key and returns the signature, as
+// with Sign.
+func (k *SecurePrivateKey) Sign(message []byte) *[SignatureSize]byte {
+	defer runtime.KeepAlive(k)
+	return sign(k.key(), modePure, nil, message, nil, nil)
+}
+
+// SignWithOptions signs the message with the private key and returns the
+// signature as per opts, as with SignWithOptions.
+func (k *SecurePrivateKey) SignWithOptions(message []byte, opts *SignOptions) *[SignatureSize]byte {
+	defer runtime.KeepAlive(k)
+	return sign(k.key(), modePure, nil, message, nil, opts)
+}
+
+// key returns the private key.  The caller must keep k reachable for as long
+// as the returned pointer is used.
+func (k *SecurePrivateKey) key() *[PrivateKeySize]byte {
+	return (*[PrivateKeySize]byte)(k.buf.Bytes())
+}
+
+// Locked returns true iff the private key is locked into memory.
+func (k *SecurePrivateKey) Locked() bool {
+	return k.buf.Locked()
+}
+
+// Destroy wipes and releases the private key.
+func (k *SecurePrivateKey) Destroy() {
+	k.buf.Destroy()
+}
@@ -0,0 +1,80 @@
+// securekey_test.go - SPHINCS-256 secure private key tests
+
+package sphincs256
+
+import (
+	"crypto/rand"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestSecurePrivateKey(t *testing.T) {
+	const msg = "Ia! Ia! Shub-Niggurath! The Black Goat of the Woods with a Thousand Young!"
+
+	pk, sk, err := GenerateSecureKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateSecureKey(): %s", err)
+	}
+	t.Logf("Locked: %v", sk.Locked())
+
+	sig := sk.Sign([]byte(msg))
+	if !Verify(pk, []byte(msg), sig) {
+		t.Fatalf("failed Verify()")
+	}
+	if *sk.SignWithOptions([]byte(msg), &SignOptions{Concurrency: 2}) != *sig {
+		t.Fatalf("SignWithOptions() does not match Sign()")
+	}
+
+	sk2 := NewSecurePrivateKey(sk.key())
+	if *sk2.PublicKey() != *pk {
+		t.Fatalf("NewSecurePrivateKey() does not match")
+	}
+	sk2.Destroy()
+	sk.Destroy()
+	sk.Destroy()
+}
+
+func TestSecurePrivateKeyGC(t *testing.T) {
+	const msg = "The colour out of space"
+
+	var raw [PrivateKeySize]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		t.Fatalf("failed to generate private key: %s", err)
+	}
+	pk := PublicKeyFromPrivateKey(&raw)
+	expected := Sign(&raw, []byte(msg))
+
+	// Collect garbage continuously, while signing with keys that become
+	// unreachable as soon as signing starts.  Releasing the memory early
+	// either crashes or corrupts the signature.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				runtime.GC()
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+
+	n := 4
+	if testing.Short() {
+		n = 2
+	}
+	for i := 0; i < n; i++ {
+		sig := NewSecurePrivateKey(&raw).Sign([]byte(msg))
+		if *sig != *expected || !Verify(pk, []byte(msg), sig) {
+			t.Fatalf("[%d]: signature mismatch", i)
+		}
+	}
+}
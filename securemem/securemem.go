@@ -0,0 +1,73 @@
+// securemem.go - Secure memory for secret key material
+
+// Package securemem implements buffers for holding secret key material, that
+// are guaranteed to be zeroized when destroyed.
+//
+// On Linux, buffers are allocated outside of the Go heap with mmap(2), are
+// surrounded by inaccessible guard pages, excluded from core dumps, and
+// locked into memory with mlock(2) so that they are never written to swap.
+// If the allocation or lock fails (eg: due to RLIMIT_MEMLOCK), the buffer
+// degrades gracefully, and Locked will return false.  On other platforms,
+// buffers are allocated on the Go heap.
+package securemem
+
+import (
+	"runtime"
+
+	"github.com/yawning/sphincs256/utils"
+)
+
+// Buffer is a fixed size buffer for secret data.  A Buffer must not be used
+// after Destroy is called, and is not safe for concurrent use.
+type Buffer struct {
+	data    []byte
+	mapping []byte
+	locked  bool
+}
+
+// New allocates a new zero filled Buffer of size bytes.
+func New(size int) *Buffer {
+	b := new(Buffer)
+	if size > 0 {
+		b.alloc(size)
+	}
+	if b.data == nil {
+		b.data = make([]byte, size)
+	}
+
+	// Wipe and release the buffer even if the caller forgets to.
+	runtime.SetFinalizer(b, (*Buffer).Destroy)
+	return b
+}
+
+// Bytes returns the buffer's contents.  The slice must not be used after
+// Destroy is called.
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// Locked returns true iff the buffer is locked into memory.
+func (b *Buffer) Locked() bool {
+	return b.locked
+}
+
+// Zero wipes the buffer's contents without releasing it.
+func (b *Buffer) Zero() {
+	utils.Zerobytes(b.data)
+}
+
+// Destroy wipes and releases the buffer.  It is safe to call Destroy more
+// than once.
+func (b *Buffer) Destroy() {
+	if b.data == nil {
+		return
+	}
+	utils.Zerobytes(b.data)
+	b.data = nil
+	if b.mapping != nil {
+		b.free()
+		b.mapping = nil
+		b.locked = false
+	}
+	runtime.SetFinalizer(b, nil)
+}
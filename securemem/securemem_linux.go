@@ -0,0 +1,45 @@
+// securemem_linux.go - Linux mmap(2)/mlock(2) backed secure memory
+
+package securemem
+
+import (
+	"os"
+	"syscall"
+)
+
+// madvDontDump is MADV_DONTDUMP, which the syscall package lacks.
+const madvDontDump = 0x10
+
+func (b *Buffer) alloc(size int) {
+	pageSize := os.Getpagesize()
+	dataLen := (size + pageSize - 1) &^ (pageSize - 1)
+
+	mapping, err := syscall.Mmap(-1, 0, dataLen+2*pageSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return
+	}
+
+	// Surround the data pages with guard pages.
+	if syscall.Mprotect(mapping[:pageSize], syscall.PROT_NONE) != nil || syscall.Mprotect(mapping[pageSize+dataLen:], syscall.PROT_NONE) != nil {
+		syscall.Munmap(mapping)
+		return
+	}
+
+	dataPages := mapping[pageSize : pageSize+dataLen]
+	syscall.Madvise(dataPages, madvDontDump) // Best effort.
+	b.locked = syscall.Mlock(dataPages) == nil
+	b.mapping = mapping
+
+	// Align the end of the data to the upper guard page, so that overflows
+	// fault immediately.
+	b.data = dataPages[dataLen-size:]
+}
+
+func (b *Buffer) free() {
+	pageSize := os.Getpagesize()
+	dataPages := b.mapping[pageSize : len(b.mapping)-pageSize]
+	if b.locked {
+		syscall.Munlock(dataPages)
+	}
+	syscall.Munmap(b.mapping)
+}
@@ -0,0 +1,37 @@
+// securemem_linux_test.go - Linux secure memory tests
+
+package securemem
+
+import (
+	"os"
+	"runtime/debug"
+	"testing"
+)
+
+func TestGuardPages(t *testing.T) {
+	b := New(100)
+	defer b.Destroy()
+	if b.mapping == nil {
+		t.Skip("mmap(2) failed, buffer is heap backed")
+	}
+	t.Logf("Locked: %v", b.Locked())
+
+	// The data must end right before the upper guard page.
+	pageSize := os.Getpagesize()
+	d := b.Bytes()
+	if &d[len(d)-1] != &b.mapping[len(b.mapping)-pageSize-1] {
+		t.Errorf("data is not aligned to the upper guard page")
+	}
+
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	for _, off := range []int{0, pageSize - 1, len(b.mapping) - pageSize, len(b.mapping) - 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("access to guard page at offset %d did not fault", off)
+				}
+			}()
+			b.mapping[off] = 0xa5
+		}()
+	}
+}
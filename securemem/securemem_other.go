@@ -0,0 +1,12 @@
+// securemem_other.go - Go heap backed secure memory
+
+// +build !linux
+
+package securemem
+
+func (b *Buffer) alloc(size int) {
+	// Fall back to the Go heap.
+}
+
+func (b *Buffer) free() {
+}
@@ -0,0 +1,46 @@
+// securemem_test.go - Secure memory tests
+
+package securemem
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuffer(t *testing.T) {
+	for _, size := range []int{0, 1, 32, 4096, 4097, 2 << 20} {
+		b := New(size)
+		d := b.Bytes()
+		if len(d) != size {
+			t.Fatalf("[%d]: Bytes() has length %d", size, len(d))
+		}
+		if !bytes.Equal(d, make([]byte, size)) {
+			t.Fatalf("[%d]: buffer is not zero filled", size)
+		}
+		for i := range d {
+			d[i] = 0xa5
+		}
+
+		b.Zero()
+		if !bytes.Equal(d, make([]byte, size)) {
+			t.Fatalf("[%d]: Zero() did not wipe the buffer", size)
+		}
+
+		b.Destroy()
+		if b.Bytes() != nil {
+			t.Fatalf("[%d]: Bytes() is non-nil after Destroy()", size)
+		}
+		b.Destroy()
+	}
+}
+
+func TestDestroyWipes(t *testing.T) {
+	// Heap backed buffers remain accessible after Destroy, so the wipe can
+	// be observed.
+	b := &Buffer{data: bytes.Repeat([]byte{0xa5}, 64)}
+	d := b.Bytes()
+	b.Destroy()
+	if !bytes.Equal(d, make([]byte, 64)) {
+		t.Fatalf("Destroy() did not wipe the buffer")
+	}
+}
@@ -0,0 +1,178 @@
+// sigfile.go - minisign style detached signature files
+
+// Package sigfile implements a human friendly detached signature file format
+// for SPHINCS-256, modeled after minisign.
+//
+// A signature file consists of three lines:
+//
+//	untrusted comment: <free form text, not covered by the signature>
+//	<base64(algorithm || sphincs256.KeyID || SPHINCS-256 signature)>
+//	trusted comment: <free form text, covered by the signature>
+//
+// The signed data is always prehashed with BLAKE-512 so that arbitrarily
+// large files can be processed in a streaming manner, and the SPHINCS-256
+// signature covers the digest and the trusted comment together.  Unlike
+// minisign, a separate "global" signature over the trusted comment is not
+// needed.
+package sigfile
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/yawning/sphincs256"
+
+	"github.com/dchest/blake512"
+)
+
+const (
+	untrustedPrefix = "untrusted comment: "
+	trustedPrefix   = "trusted comment: "
+
+	algPrehashed = "SP"
+
+	messageContext = "sphincs256-sigfile-v1\x00"
+)
+
+var (
+	// ErrKeyIDMismatch is the error returned when a signature file was made
+	// with a different key than the one used to verify it.
+	ErrKeyIDMismatch = errors.New("sigfile: signature key ID does not match public key")
+
+	// ErrVerificationFailed is the error returned when a signature file's
+	// signature is invalid.
+	ErrVerificationFailed = errors.New("sigfile: signature verification failed")
+)
+
+// Signature is a parsed signature file.
+type Signature struct {
+	UntrustedComment string
+	TrustedComment   string
+	KeyID            sphincs256.KeyID
+	Signature        *[sphincs256.SignatureSize]byte
+}
+
+// Sign reads r until EOF and signs its contents along with trustedComment
+// using privateKey.
+func Sign(privateKey *[sphincs256.PrivateKeySize]byte, r io.Reader, trustedComment, untrustedComment string) (*Signature, error) {
+	if err := validateComment(trustedComment); err != nil {
+		return nil, err
+	}
+	if err := validateComment(untrustedComment); err != nil {
+		return nil, err
+	}
+
+	s := &Signature{
+		UntrustedComment: untrustedComment,
+		TrustedComment:   trustedComment,
+		KeyID:            (*sphincs256.PublicKey)(sphincs256.PublicKeyFromPrivateKey(privateKey)).KeyID(),
+	}
+	m, err := s.message(r)
+	if err != nil {
+		return nil, err
+	}
+	s.Signature = sphincs256.Sign(privateKey, m)
+	return s, nil
+}
+
+// Verify reads r until EOF and verifies its contents and the trusted comment
+// against sig using publicKey.
+func Verify(publicKey *[sphincs256.PublicKeySize]byte, r io.Reader, sig *Signature) error {
+	if sig.Signature == nil {
+		return ErrVerificationFailed
+	}
+	if (*sphincs256.PublicKey)(publicKey).KeyID() != sig.KeyID {
+		return ErrKeyIDMismatch
+	}
+	if err := validateComment(sig.TrustedComment); err != nil {
+		return err
+	}
+
+	m, err := sig.message(r)
+	if err != nil {
+		return err
+	}
+	if !sphincs256.Verify(publicKey, m, sig.Signature) {
+		return ErrVerificationFailed
+	}
+	return nil
+}
+
+// message returns the message that is actually signed by SPHINCS-256.
+func (s *Signature) message(r io.Reader) ([]byte, error) {
+	h := blake512.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+
+	m := make([]byte, 0, len(messageContext)+len(algPrehashed)+sphincs256.KeyIDSize+blake512.Size+len(s.TrustedComment))
+	m = append(m, messageContext...)
+	m = append(m, algPrehashed...)
+	m = append(m, s.KeyID[:]...)
+	m = h.Sum(m)
+	return append(m, s.TrustedComment...), nil
+}
+
+// Bytes returns the serialized signature file.
+func (s *Signature) Bytes() []byte {
+	raw := make([]byte, 0, len(algPrehashed)+sphincs256.KeyIDSize+sphincs256.SignatureSize)
+	raw = append(raw, algPrehashed...)
+	raw = append(raw, s.KeyID[:]...)
+	raw = append(raw, s.Signature[:]...)
+
+	var buf bytes.Buffer
+	buf.WriteString(untrustedPrefix + s.UntrustedComment + "\n")
+	buf.WriteString(base64.StdEncoding.EncodeToString(raw) + "\n")
+	buf.WriteString(trustedPrefix + s.TrustedComment + "\n")
+	return buf.Bytes()
+}
+
+// Parse parses a serialized signature file.
+func Parse(b []byte) (*Signature, error) {
+	// Comments are not length limited, so split the lines directly rather
+	// than with a bufio.Scanner.
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	if len(lines) != 3 {
+		return nil, fmt.Errorf("sigfile: malformed signature file")
+	}
+
+	s := new(Signature)
+	if !strings.HasPrefix(lines[0], untrustedPrefix) {
+		return nil, fmt.Errorf("sigfile: missing untrusted comment")
+	}
+	s.UntrustedComment = lines[0][len(untrustedPrefix):]
+	if !strings.HasPrefix(lines[2], trustedPrefix) {
+		return nil, fmt.Errorf("sigfile: missing trusted comment")
+	}
+	s.TrustedComment = lines[2][len(trustedPrefix):]
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		return nil, fmt.Errorf("sigfile: invalid signature encoding: %v", err)
+	}
+	if len(raw) != len(algPrehashed)+sphincs256.KeyIDSize+sphincs256.SignatureSize {
+		return nil, fmt.Errorf("sigfile: invalid signature length: %d", len(raw))
+	}
+	if string(raw[:len(algPrehashed)]) != algPrehashed {
+		return nil, fmt.Errorf("sigfile: unsupported signature algorithm: %q", raw[:len(algPrehashed)])
+	}
+	raw = raw[len(algPrehashed):]
+	copy(s.KeyID[:], raw[:sphincs256.KeyIDSize])
+	s.Signature = new([sphincs256.SignatureSize]byte)
+	copy(s.Signature[:], raw[sphincs256.KeyIDSize:])
+	return s, nil
+}
+
+func validateComment(c string) error {
+	if strings.ContainsAny(c, "\r\n") {
+		return fmt.Errorf("sigfile: comments may not contain line breaks")
+	}
+	return nil
+}
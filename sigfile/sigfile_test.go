@@ -0,0 +1,131 @@
+// sigfile_test.go - Detached signature file tests
+
+package sigfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/yawning/sphincs256"
+)
+
+func TestSignVerify(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	// Large enough that it would be silly to not stream it.
+	data := make([]byte, 1<<20)
+	if _, err = rand.Read(data); err != nil {
+		t.Fatalf("failed to generate test data: %s", err)
+	}
+
+	const trusted = "timestamp:1474408800\tfile:necronomicon.tar.gz"
+	const untrusted = "signature from sphincs256 secret key"
+	s, err := Sign(sk, bytes.NewReader(data), trusted, untrusted)
+	if err != nil {
+		t.Fatalf("failed Sign(): %s", err)
+	}
+
+	b := s.Bytes()
+	if !strings.HasPrefix(string(b), "untrusted comment: "+untrusted+"\n") {
+		t.Errorf("unexpected signature file prefix")
+	}
+
+	s2, err := Parse(b)
+	if err != nil {
+		t.Fatalf("failed Parse(): %s", err)
+	}
+	if s2.TrustedComment != trusted || s2.UntrustedComment != untrusted || s2.KeyID != (*sphincs256.PublicKey)(pk).KeyID() || *s2.Signature != *s.Signature {
+		t.Fatalf("signature file round trip mismatch")
+	}
+	if err = Verify(pk, bytes.NewReader(data), s2); err != nil {
+		t.Fatalf("failed Verify(): %s", err)
+	}
+
+	// The untrusted comment is not covered by the signature.
+	s2.UntrustedComment = "something else"
+	if err = Verify(pk, bytes.NewReader(data), s2); err != nil {
+		t.Errorf("failed Verify() with an altered untrusted comment: %s", err)
+	}
+
+	// The trusted comment is.
+	s2.TrustedComment = trusted + " "
+	if err = Verify(pk, bytes.NewReader(data), s2); err != ErrVerificationFailed {
+		t.Errorf("Verify() with an altered trusted comment: %v", err)
+	}
+	s2.TrustedComment = trusted
+
+	data[len(data)-1] ^= 1
+	if err = Verify(pk, bytes.NewReader(data), s2); err != ErrVerificationFailed {
+		t.Errorf("Verify() with altered data: %v", err)
+	}
+	data[len(data)-1] ^= 1
+
+	otherPk, _, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	if err = Verify(otherPk, bytes.NewReader(data), s2); err != ErrKeyIDMismatch {
+		t.Errorf("Verify() with the wrong public key: %v", err)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	_, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	if _, err = Sign(sk, strings.NewReader(""), "multi\nline", ""); err == nil {
+		t.Errorf("Sign() accepted a multi-line trusted comment")
+	}
+
+	s, err := Sign(sk, strings.NewReader(""), "", "")
+	if err != nil {
+		t.Fatalf("failed Sign(): %s", err)
+	}
+	lines := strings.Split(string(s.Bytes()), "\n")
+
+	malformed := []string{
+		lines[0] + "\n" + lines[1] + "\n",
+		lines[1] + "\n" + lines[0] + "\n" + lines[2] + "\n",
+		lines[0] + "\n" + lines[1][:len(lines[1])-4] + "\n" + lines[2] + "\n",
+		lines[0] + "\n" + lines[1] + "\n" + lines[2] + "\nextra\n",
+	}
+	for i, m := range malformed {
+		if _, err = Parse([]byte(m)); err == nil {
+			t.Errorf("[%d]: Parse() accepted a malformed signature file", i)
+		}
+	}
+	if err = Verify(sphincs256.PublicKeyFromPrivateKey(sk), strings.NewReader(""), &Signature{KeyID: s.KeyID}); err != ErrVerificationFailed {
+		t.Errorf("Verify() without a signature: %v", err)
+	}
+}
+
+func TestLongComments(t *testing.T) {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	trusted := strings.Repeat("t", 256*1024)
+	untrusted := strings.Repeat("u", 256*1024)
+	s, err := Sign(sk, strings.NewReader("data"), trusted, untrusted)
+	if err != nil {
+		t.Fatalf("failed Sign(): %s", err)
+	}
+	s2, err := Parse(s.Bytes())
+	if err != nil {
+		t.Fatalf("failed Parse(): %s", err)
+	}
+	if s2.TrustedComment != trusted || s2.UntrustedComment != untrusted {
+		t.Fatalf("comment round trip mismatch")
+	}
+	if err = Verify(pk, strings.NewReader("data"), s2); err != nil {
+		t.Fatalf("failed Verify(): %s", err)
+	}
+}
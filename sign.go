@@ -7,8 +7,10 @@ package sphincs256
 import (
 	"crypto/subtle"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/yawning/sphincs256/hash"
 	"github.com/yawning/sphincs256/horst"
@@ -25,9 +27,18 @@ const (
 	// PrivateKeySize is the length of a SPHINCS-256 private key in bytes.
 	PrivateKeySize = seedBytes + PublicKeySize - hash.Size + skRandSeedBytes
 
+	// RandBytes is the amount of randomness SignWithRand reads per
+	// signature in bytes.
+	RandBytes = 32
+
 	// SignatureSize is the length of a SPHINCS-256 signature in bytes.
 	SignatureSize = messageHashSeedBytes + (totalTreeHeight+7)/8 + horst.SigBytes + (totalTreeHeight/subtreeHeight)*wots.SigBytes + totalTreeHeight*hash.Size
 
+	// HypertreeHeight is the total height of the hypertree.  Each signature
+	// uses one of the 2^HypertreeHeight HORST key pairs, picked
+	// pseudorandomly.
+	HypertreeHeight = totalTreeHeight
+
 	subtreeHeight        = 5
 	totalTreeHeight      = 60
 	nLevels              = totalTreeHeight / subtreeHeight
@@ -37,13 +48,29 @@ const (
 	nMasks               = 2 * horst.LogT // has to be the max of (2*(subtreeHeight+wotsLogL)) and (wotsW-1) and 2*horstLogT
 )
 
+// ErrFaultDetected is the error returned by SignHardened when the signature
+// fails to verify.
+var ErrFaultDetected = errors.New("sphincs256: fault detected while signing")
+
 type leafaddr struct {
 	level   int
 	subtree uint64
 	subleaf int
 }
 
-func getSeed(seed, sk []byte, a *leafaddr) {
+// treeScratch is the working memory used to build hypertree subtrees.  It is
+// too large to comfortably live on the stack.
+type treeScratch struct {
+	varlen      hash.VarlenHasher
+	wots        wots.Scratch
+	seeds       [(1 << subtreeHeight) * seedBytes]byte
+	wotsPks     [(1 << subtreeHeight) * wots.L * hash.Size]byte
+	tree        [2 * (1 << subtreeHeight) * hash.Size]byte
+	stack       [(subtreeHeight + 1) * hash.Size]byte
+	stackLevels [subtreeHeight + 1]uint
+}
+
+func (ts *treeScratch) getSeed(seed, sk []byte, a *leafaddr) {
 //	seed = seed[:seedBytes]
 
 	var buffer [seedBytes + 8]byte
@@ -57,7 +84,8 @@ func getSeed(seed, sk []byte, a *leafaddr) {
 	t |= uint64(a.subleaf) << 59
 
 	binary.LittleEndian.PutUint64(buffer[seedBytes:], t)
-	hash.Varlen(seed, buffer[:])
+	ts.varlen.Varlen(seed, buffer[:])
+	utils.Zerobytes(buffer[:])
 }
 
 func lTree(leaf, wotsPk, masks []byte) {
@@ -77,25 +105,26 @@ func lTree(leaf, wotsPk, masks []byte) {
 	copy(leaf[:hash.Size], wotsPk[:])
 }
 
-func genLeafWots(leaf, masks, sk []byte, a *leafaddr) {
+func (ts *treeScratch) genLeafWots(leaf, masks, sk []byte, a *leafaddr) {
 	var seed [seedBytes]byte
 	var pk [wots.L * hash.Size]byte
 
-	getSeed(seed[:], sk, a)
-	wots.Pkgen(pk[:], seed[:], masks)
+	ts.getSeed(seed[:], sk, a)
+	wots.Pkgen(pk[:], seed[:], masks, &ts.wots)
+	utils.Zerobytes(seed[:])
 	lTree(leaf, pk[:], masks)
 }
 
-func treehash(node []byte, height int, sk []byte, leaf *leafaddr, masks []byte) {
+func (ts *treeScratch) treehash(node []byte, height int, sk []byte, leaf *leafaddr, masks []byte) {
 	a := *leaf
-	stack := make([]byte, (height+1)*hash.Size)
-	stacklevels := make([]uint, height+1)
+	stack := ts.stack[:(height+1)*hash.Size]
+	stacklevels := ts.stackLevels[:height+1]
 	var stackoffset, maskoffset uint
 
 	lastnode := a.subleaf + (1 << uint(height))
 
 	for ; a.subleaf < lastnode; a.subleaf++ {
-		genLeafWots(stack[stackoffset*hash.Size:], masks, sk, &a)
+		ts.genLeafWots(stack[stackoffset*hash.Size:], masks, sk, &a)
 		stacklevels[stackoffset] = 0
 		stackoffset++
 		for stackoffset > 1 && stacklevels[stackoffset-1] == stacklevels[stackoffset-2] {
@@ -135,19 +164,20 @@ func validateAuthpath(root, leaf *[hash.Size]byte, leafidx uint, authpath, masks
 	hash.Hash_2n_n_mask(root[:], buffer[:], masks[2*(wots.LogL+height-1)*hash.Size:])
 }
 
-func computeAuthpathWots(root *[hash.Size]byte, authpath []byte, a *leafaddr, sk, masks []byte, height uint) {
+func (ts *treeScratch) computeAuthpathWots(root *[hash.Size]byte, authpath []byte, a *leafaddr, sk, masks []byte, height uint) {
 	ta := *a
-	var tree [2 * (1 << subtreeHeight) * hash.Size]byte
-	var seed [(1 << subtreeHeight) * seedBytes]byte
-	var pk [(1 << subtreeHeight) * wots.L * hash.Size]byte
+	tree := ts.tree[:]
+	seed := ts.seeds[:]
+	pk := ts.wotsPks[:]
 
 	// Level 0.
 	for ta.subleaf = 0; ta.subleaf < 1<<subtreeHeight; ta.subleaf++ {
-		getSeed(seed[ta.subleaf*seedBytes:], sk, &ta)
+		ts.getSeed(seed[ta.subleaf*seedBytes:], sk, &ta)
 	}
 	for ta.subleaf = 0; ta.subleaf < 1<<subtreeHeight; ta.subleaf++ {
-		wots.Pkgen(pk[ta.subleaf*wots.L*hash.Size:], seed[ta.subleaf*seedBytes:], masks)
+		wots.Pkgen(pk[ta.subleaf*wots.L*hash.Size:], seed[ta.subleaf*seedBytes:], masks, &ts.wots)
 	}
+	utils.Zerobytes(seed)
 	for ta.subleaf = 0; ta.subleaf < 1<<subtreeHeight; ta.subleaf++ {
 		lTree(tree[(1<<subtreeHeight)*hash.Size+ta.subleaf*hash.Size:], pk[ta.subleaf*wots.L*hash.Size:], masks)
 	}
@@ -181,42 +211,200 @@ func GenerateKey(rand io.Reader) (publicKey *[PublicKeySize]byte, privateKey *[P
 	if err != nil {
 		return nil, nil, err
 	}
-	copy(publicKey[:nMasks*hash.Size], privateKey[seedBytes:])
+	derivePublicKey(publicKey[:], privateKey[:])
+	return
+}
+
+// PublicKeyFromPrivateKey returns the public key corresponding to privateKey.
+func PublicKeyFromPrivateKey(privateKey *[PrivateKeySize]byte) *[PublicKeySize]byte {
+	publicKey := new([PublicKeySize]byte)
+	derivePublicKey(publicKey[:], privateKey[:])
+	return publicKey
+}
+
+// derivePublicKey derives the public key pk from sk, with temporary working
+// memory.
+func derivePublicKey(pk, sk []byte) {
+	ts := new(treeScratch)
+	ts.derivePublicKey(pk, sk)
+	ts.wots.Destroy()
+}
+
+func (ts *treeScratch) derivePublicKey(pk, sk []byte) {
+	copy(pk[:nMasks*hash.Size], sk[seedBytes:])
 
 	// Initialization of top-subtree address.
 	a := leafaddr{level: nLevels - 1, subtree: 0, subleaf: 0}
 
 	// Construct top subtree.
-	treehash(publicKey[nMasks*hash.Size:], subtreeHeight, privateKey[:], &a, publicKey[:])
-	return
+	ts.treehash(pk[nMasks*hash.Size:], subtreeHeight, sk, &a, pk)
 }
 
 // Sign signs the message with privateKey and returns the signature.
 func Sign(privateKey *[PrivateKeySize]byte, message []byte) *[SignatureSize]byte {
-	var sm [SignatureSize]byte
+	return sign(privateKey, modePure, nil, message, nil, nil)
+}
+
+// SignOptions are the optional parameters for SignWithOptions.
+type SignOptions struct {
+	// Concurrency is the maximum number of goroutines used to build the
+	// HORST tree, which dominates the cost of signing.  If Concurrency <= 1,
+	// the signature is computed on the calling goroutine only.
+	// runtime.GOMAXPROCS(0) is a reasonable value to use on an otherwise
+	// idle machine.
+	Concurrency int
+
+	// Tracer, if non-nil, is called back as the signature is computed.
+	Tracer Tracer
+}
+
+func (o *SignOptions) concurrency() int {
+	if o == nil {
+		return 1
+	}
+	return o.Concurrency
+}
+
+// SignWithOptions signs the message with privateKey and returns the
+// signature, as per opts.  The signature is identical to the one returned by
+// Sign.
+func SignWithOptions(privateKey *[PrivateKeySize]byte, message []byte, opts *SignOptions) *[SignatureSize]byte {
+	return sign(privateKey, modePure, nil, message, nil, opts)
+}
+
+// SignWithRand signs the message with privateKey, mixing in randomness from
+// rand, and returns the signature.  The resulting signatures are verified
+// with Verify, exactly like those from Sign.
+//
+// Sign derives the HORST leaf index and the message randomizer R solely from
+// the secret PRF key and the message, so signing the same message twice
+// repeats the exact same computation.  That makes it easier to mount fault
+// attacks, where an attacker compares correct and faulty signatures over
+// the same message to recover one-time secret key material.  SignWithRand
+// additionally feeds RandBytes of fresh randomness into the derivation
+// (similar to the "hedged" variant of SLH-DSA), so the computations differ
+// every time.
+//
+// The secret PRF key is still mixed in, so a weak or compromised rand only
+// degrades to the security of Sign, and never leaks the private key.  The
+// leaf index remains pseudorandom, but every signature, including re-signing
+// a message that was already signed, picks a fresh HORST key pair and counts
+// against the per-key signature budget, where Sign would repeat an existing
+// signature for free.  Signatures are also no longer reproducible, which
+// breaks known answer testing and signature deduplication.
+//
+// The randomness is hashed between the PRF key and the message without
+// framing.  This is unambiguous, as it is always exactly RandBytes long.  The
+// input can still coincide with that of Sign over the randomness followed by
+// the message, but that only repeats a leaf index and R, with a message hash
+// that still covers the actual message, which is no worse than the leaf
+// index collisions that happen anyway.
+func SignWithRand(rand io.Reader, privateKey *[PrivateKeySize]byte, message []byte) (*[SignatureSize]byte, error) {
+	var optRand [RandBytes]byte
+	if _, err := io.ReadFull(rand, optRand[:]); err != nil {
+		return nil, err
+	}
+	return sign(privateKey, modePure, nil, message, optRand[:], nil), nil
+}
+
+// SignHardened signs the message with privateKey and returns the signature,
+// after checking that the signature verifies with publicKey, which must be
+// the known good public key corresponding to privateKey (eg: as returned by
+// GenerateKey).
+//
+// Hash-based signatures are vulnerable to fault attacks, where a single
+// faulty subtree root computation causes a WOTS key pair to sign two
+// different values, leaking enough one-time secret key material for
+// universal forgeries.  If verification fails, the signature is wiped and
+// ErrFaultDetected is returned, so faulty signatures are never released.
+//
+// The verification recomputes every layer's root from the signature via an
+// independent code path, and the message hash from publicKey instead of the
+// internally regenerated public key, at a small fraction of the cost of
+// signing.
+func SignHardened(publicKey *[PublicKeySize]byte, privateKey *[PrivateKeySize]byte, message []byte) (*[SignatureSize]byte, error) {
+	sig := sign(privateKey, modePure, nil, message, nil, nil)
+	if !Verify(publicKey, message, sig) {
+		utils.Zerobytes(sig[:])
+		return nil, ErrFaultDetected
+	}
+	return sig, nil
+}
+
+// SignTo signs the message with privateKey and writes the signature to sig,
+// using scratch as working memory (if scratch is nil, it is allocated).  The
+// signature is identical to the one returned by Sign.
+//
+// Signing with a Scratch that has been used before does not allocate, and
+// keeps the goroutine's stack small.
+func SignTo(sig *[SignatureSize]byte, privateKey *[PrivateKeySize]byte, message []byte, scratch *Scratch) {
+	signTo(sig, privateKey, modePure, nil, message, nil, nil, scratch)
+}
+
+// The signature modes.  The mode is XORed into the first byte of the secret
+// PRF key when deriving the leaf index and R, and into the first byte of the
+// copy of the public key that follows R in the message hash, in both signTo
+// and verify.  Neither byte is taken from the signature or the message, so no
+// choice of message or R in one mode reproduces the hash inputs of another.
+const (
+	modePure = iota
+	modePrehash
+	modeContext
+)
+
+// sign signs dom || message in mode, where dom is the encoding of the mode
+// specific parameters for the modes that have them, but without copying the
+// message.
+func sign(privateKey *[PrivateKeySize]byte, mode byte, dom, message, optRand []byte, opts *SignOptions) *[SignatureSize]byte {
+	sm := new([SignatureSize]byte)
+	signTo(sm, privateKey, mode, dom, message, optRand, opts, nil)
+	return sm
+}
+
+func signTo(sm *[SignatureSize]byte, privateKey *[PrivateKeySize]byte, mode byte, dom, message, optRand []byte, opts *SignOptions, scr *Scratch) {
 	var leafidx uint64
 	var r [messageHashSeedBytes]byte
 	var mH []byte
-	var tsk [PrivateKeySize]byte
 	var root [hash.Size]byte
 	var seed [seedBytes]byte
 	var masks [nMasks * hash.Size]byte
+	var start, layerStart time.Time
+
+	tr := opts.tracer()
+	if tr != nil {
+		start = time.Now()
+		tr.OnSignStart()
+	}
+
+	if scr == nil {
+		scr = new(Scratch)
+		defer scr.Destroy()
+	}
+	scr.init()
 
+	// Keep the working copy of the private key in secure memory.
+	tsk := (*[PrivateKeySize]byte)(scr.tskBuf.Bytes())
+	defer scr.tskBuf.Zero()
 	copy(tsk[:], privateKey[:])
 
-	// Create leafidx deterministically.
+	// Create leafidx deterministically (modulo optRand).
 	{
 		// Shift scratch upwards for convinience.
 		scratch := sm[SignatureSize-skRandSeedBytes:]
 
 		// Copy secret random seed to scratch.
 		copy(scratch[:skRandSeedBytes], tsk[PrivateKeySize-skRandSeedBytes:])
+		scratch[0] ^= mode
 
 		// XXX: Why Blake 512?
-		h := blake512.New()
+		h := scr.h
+		h.Reset()
 		h.Write(scratch[:skRandSeedBytes])
+		h.Write(optRand)
+		h.Write(dom)
 		h.Write(message)
-		rnd := h.Sum(nil)
+		rnd := h.Sum(scr.rnd[:0])
+		defer utils.Zerobytes(rnd)
 
 		// XXX/Yawning: The original code doesn't do endian conversion when
 		// using rnd.  This is probably wrong, so do the Right Thing(TM).
@@ -230,15 +418,14 @@ func Sign(privateKey *[PrivateKeySize]byte, message []byte) *[SignatureSize]byte
 		copy(scratch[:], r[:])
 
 		// Construct and copy pk.
-		a := leafaddr{level: nLevels - 1, subtree: 0, subleaf: 0}
-		pk := scratch[messageHashSeedBytes:]
-		copy(pk[:nMasks*hash.Size], tsk[seedBytes:])
-		treehash(pk[nMasks*hash.Size:], subtreeHeight, tsk[:], &a, pk)
+		scr.tree.derivePublicKey(scratch[messageHashSeedBytes:], tsk[:])
+		scratch[messageHashSeedBytes] ^= mode
 
 		h.Reset()
 		h.Write(scratch[:messageHashSeedBytes+PublicKeySize])
+		h.Write(dom)
 		h.Write(message)
-		mH = h.Sum(nil)
+		mH = h.Sum(scr.mH[:0])
 	}
 
 	// Use unique value $d$ for HORST address.
@@ -255,45 +442,85 @@ func Sign(privateKey *[PrivateKeySize]byte, message []byte) *[SignatureSize]byte
 	}
 	sigp = sigp[(totalTreeHeight+7)/8:]
 
-	getSeed(seed[:], tsk[:], &a)
-	horst.Sign(sigp, &root, message, &seed, masks[:], mH)
+	if tr != nil {
+		layerStart = time.Now()
+		tr.OnHORSTStart()
+	}
+	scr.tree.getSeed(seed[:], tsk[:], &a)
+	horst.Sign(sigp, &root, message, &seed, masks[:], mH, opts.concurrency(), &scr.horst)
 	sigp = sigp[horst.SigBytes:]
+	if tr != nil {
+		tr.OnHORSTEnd(time.Since(layerStart))
+	}
 
 	for i := 0; i < nLevels; i++ {
 		a.level = i
+		if tr != nil {
+			layerStart = time.Now()
+			tr.OnLayerStart(i)
+		}
 
-		getSeed(seed[:], tsk[:], &a) // XXX: Don't use the same address as for horst_sign here!
-		wots.Sign(sigp, &root, &seed, masks[:])
+		scr.tree.getSeed(seed[:], tsk[:], &a) // XXX: Don't use the same address as for horst_sign here!
+		wots.Sign(sigp, &root, &seed, masks[:], &scr.tree.wots)
 		sigp = sigp[wots.SigBytes:]
 
-		computeAuthpathWots(&root, sigp, &a, tsk[:], masks[:], subtreeHeight)
+		scr.tree.computeAuthpathWots(&root, sigp, &a, tsk[:], masks[:], subtreeHeight)
 		sigp = sigp[subtreeHeight*hash.Size:]
 
 		a.subleaf = int(a.subtree & ((1 << subtreeHeight) - 1))
 		a.subtree >>= subtreeHeight
+		if tr != nil {
+			tr.OnLayerEnd(i, time.Since(layerStart))
+		}
 	}
+	utils.Zerobytes(seed[:])
 
-	utils.Zerobytes(tsk[:])
+	if tr != nil {
+		tr.OnSignEnd(time.Since(start))
+	}
+}
 
-	return &sm
+// LeafIndex returns the hypertree leaf index stored in the signature, which
+// identifies the HORST key pair used to sign the message.  The signature is
+// not verified.
+func LeafIndex(signature *[SignatureSize]byte) uint64 {
+	var leafidx uint64
+	for i := uint64(0); i < (totalTreeHeight+7)/8; i++ {
+		leafidx |= uint64(signature[messageHashSeedBytes+i]) << (8 * i)
+	}
+	return leafidx
 }
 
 // Verify takes a public key, message and signature and returns true if the
 // signature is valid.
 func Verify(publicKey *[PublicKeySize]byte, message []byte, signature *[SignatureSize]byte) bool {
+	return verify(publicKey, modePure, nil, message, signature, nil)
+}
+
+func verify(publicKey *[PublicKeySize]byte, mode byte, dom, message []byte, signature *[SignatureSize]byte, cache *nodeCache) bool {
 	var leafidx uint64
 	var wotsPk [wots.L * hash.Size]byte
 	var pkhash [hash.Size]byte
 	var root [hash.Size]byte
 	var tpk [PublicKeySize]byte
+	var r [messageHashSeedBytes]byte
 	var mH []byte
 
+	// Subtree roots computed on the way up, only committed to the cache once
+	// they have been authenticated against the public key.
+	var pending [nLevels - 1]nodeEntry
+	nPending := 0
+
 	copy(tpk[:], publicKey[:])
 
 	// Construct message hash.
+	copy(r[:], signature[:messageHashSeedBytes])
 	h := blake512.New()
-	h.Write(signature[:messageHashSeedBytes])
+	h.Write(r[:])
+	tpk[0] ^= mode
 	h.Write(tpk[:])
+	tpk[0] ^= mode
+	h.Write(dom)
 	h.Write(message)
 	mH = h.Sum(nil)
 
@@ -317,10 +544,31 @@ func Verify(publicKey *[PublicKeySize]byte, message []byte, signature *[Signatur
 		validateAuthpath(&root, &pkhash, uint(leafidx&0x1f), sigp, tpk[:], subtreeHeight)
 		leafidx >>= 5
 		sigp = sigp[subtreeHeight*hash.Size:]
+
+		// The root of the top subtree is the public key root, so there is
+		// no point in caching it.
+		if cache != nil && i < nLevels-1 {
+			e := &pending[nPending]
+			e.addr = nodeAddr{level: i, subtree: leafidx}
+			e.root = root
+			hash.Varlen(e.tail[:], sigp)
+			if cache.lookup(e) {
+				// Everything above this node was already authenticated.
+				cache.insert(pending[:nPending])
+				return true
+			}
+			nPending++
+		}
 	}
 
 	tpkRewt := tpk[nMasks*hash.Size:]
-	return subtle.ConstantTimeCompare(root[:], tpkRewt) == 1
+	if subtle.ConstantTimeCompare(root[:], tpkRewt) != 1 {
+		return false
+	}
+	if cache != nil {
+		cache.insert(pending[:nPending])
+	}
+	return true
 }
 
 // Open takes a signed message and public key and returns the message if the
@@ -6,14 +6,18 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/base64"
+	"runtime"
 	"testing"
 )
 
 func TestGenerateKey(t *testing.T) {
-	_, _, err := GenerateKey(rand.Reader)
+	pk, sk, err := GenerateKey(rand.Reader)
 	if err != nil {
 		t.Fatalf("failed GenerateKey(): %s", err)
 	}
+	if *PublicKeyFromPrivateKey(sk) != *pk {
+		t.Fatalf("PublicKeyFromPrivateKey() does not match GenerateKey()")
+	}
 }
 
 func TestSignVerifyOpen(t *testing.T) {
@@ -110,6 +114,7 @@ func BenchmarkSign(b *testing.B) {
 	}
 	b.ResetTimer()
 
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		sig := Sign(sk, benchMsg)
 		b.StopTimer()
@@ -121,6 +126,36 @@ func BenchmarkSign(b *testing.B) {
 	}
 }
 
+func BenchmarkSignTo(b *testing.B) {
+	_, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatalf("failed GenerateKey(): %s", err)
+	}
+	scratch := NewScratch()
+	defer scratch.Destroy()
+	var sig [SignatureSize]byte
+	SignTo(&sig, sk, benchMsg, scratch)
+	b.ResetTimer()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		SignTo(&sig, sk, benchMsg, scratch)
+	}
+}
+
+func BenchmarkSignConcurrent(b *testing.B) {
+	_, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		b.Fatalf("failed GenerateKey(): %s", err)
+	}
+	opts := &SignOptions{Concurrency: runtime.GOMAXPROCS(0)}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		SignWithOptions(sk, benchMsg, opts)
+	}
+}
+
 func BenchmarkVerify(b *testing.B) {
 	pk, sk, err := GenerateKey(rand.Reader)
 	if err != nil {
@@ -136,3 +171,156 @@ func BenchmarkVerify(b *testing.B) {
 		b.StartTimer()
 	}
 }
+
+func TestSignWithRand(t *testing.T) {
+	const msg = "The world is indeed comic, but the joke is on mankind."
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	sig1, err := SignWithRand(rand.Reader, sk, []byte(msg))
+	if err != nil {
+		t.Fatalf("failed SignWithRand(): %s", err)
+	}
+	sig2, err := SignWithRand(rand.Reader, sk, []byte(msg))
+	if err != nil {
+		t.Fatalf("failed SignWithRand(): %s", err)
+	}
+	if *sig1 == *sig2 {
+		t.Fatalf("SignWithRand() produced identical signatures")
+	}
+	if *sig1 == *Sign(sk, []byte(msg)) {
+		t.Fatalf("SignWithRand() produced the deterministic signature")
+	}
+
+	for i, sig := range []*[SignatureSize]byte{sig1, sig2} {
+		if !Verify(pk, []byte(msg), sig) {
+			t.Errorf("failed Verify() [%d]", i)
+		}
+	}
+
+	if _, err = SignWithRand(bytes.NewReader(nil), sk, []byte(msg)); err == nil {
+		t.Errorf("SignWithRand() succeeded with an empty entropy source")
+	}
+}
+
+func TestSignHardened(t *testing.T) {
+	const msg = "Almost nobody dances sober, unless they happen to be insane."
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	sig, err := SignHardened(pk, sk, []byte(msg))
+	if err != nil {
+		t.Fatalf("failed SignHardened(): %s", err)
+	}
+	if *sig != *Sign(sk, []byte(msg)) {
+		t.Fatalf("SignHardened() does not match Sign()")
+	}
+
+	// Simulate a fault in the private key's bitmasks, which makes the
+	// regenerated public key and every root differ from the genuine ones.
+	faulty := *sk
+	faulty[seedBytes] ^= 0x01
+	if sig, err = SignHardened(pk, &faulty, []byte(msg)); err != ErrFaultDetected || sig != nil {
+		t.Fatalf("SignHardened() with a faulty private key: %v", err)
+	}
+}
+
+func TestSignWipesScratch(t *testing.T) {
+	const msg = "What has been said is not all."
+
+	_, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	scratch := NewScratch()
+	defer scratch.Destroy()
+	var sig [SignatureSize]byte
+	SignTo(&sig, sk, []byte(msg), scratch)
+
+	for _, v := range []struct {
+		name string
+		b    []byte
+	}{
+		{"private key copy", scratch.tskBuf.Bytes()},
+		{"rnd", scratch.rnd[:]},
+		{"seeds", scratch.tree.seeds[:]},
+	} {
+		if !bytes.Equal(v.b, make([]byte, len(v.b))) {
+			t.Errorf("SignTo() left the %s intact", v.name)
+		}
+	}
+}
+
+func TestSignWithOptions(t *testing.T) {
+	const msg = "We live on a placid island of ignorance in the midst of black seas of infinity."
+
+	_, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	expected := Sign(sk, []byte(msg))
+	for _, n := range []int{0, 2, 7, 1000} {
+		sig := SignWithOptions(sk, []byte(msg), &SignOptions{Concurrency: n})
+		if *sig != *expected {
+			t.Errorf("[%d]: SignWithOptions() does not match Sign()", n)
+		}
+	}
+}
+
+func TestSignTo(t *testing.T) {
+	const msg = "Searchers after horror haunt strange, far places."
+
+	_, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	expected := Sign(sk, []byte(msg))
+
+	var sig [SignatureSize]byte
+	SignTo(&sig, sk, []byte(msg), nil)
+	if sig != *expected {
+		t.Fatalf("SignTo() without a Scratch does not match Sign()")
+	}
+
+	scratch := NewScratch()
+	defer scratch.Destroy()
+	for i := 0; i < 2; i++ {
+		sig = [SignatureSize]byte{}
+		SignTo(&sig, sk, []byte(msg), scratch)
+		if sig != *expected {
+			t.Fatalf("SignTo() does not match Sign() [%d]", i)
+		}
+	}
+
+	// The zero value, and a Scratch that has been destroyed, are usable.
+	var zero Scratch
+	for i := 0; i < 2; i++ {
+		sig = [SignatureSize]byte{}
+		SignTo(&sig, sk, []byte(msg), &zero)
+		if sig != *expected {
+			t.Fatalf("SignTo() with a zero Scratch does not match Sign() [%d]", i)
+		}
+		zero.Destroy()
+	}
+
+	// The race detector's instrumentation allocates.
+	if raceEnabled {
+		return
+	}
+
+	m := []byte(msg)
+	allocs := testing.AllocsPerRun(2, func() {
+		SignTo(&sig, sk, m, scratch)
+	})
+	if allocs != 0 {
+		t.Errorf("SignTo() allocated: %v", allocs)
+	}
+}
@@ -0,0 +1,70 @@
+// signer.go - SPHINCS-256 crypto.Signer
+
+package sphincs256
+
+import (
+	"crypto"
+	"io"
+)
+
+// Options can be used with Signer.Sign to select pure or pre-hashed
+// signatures.
+type Options struct {
+	// Hash is the digest algorithm for pre-hashed signatures (see
+	// SignPrehashed), or 0 for pure signatures.
+	Hash crypto.Hash
+}
+
+// HashFunc returns o.Hash (0 if o is nil), and implements crypto.SignerOpts.
+func (o *Options) HashFunc() crypto.Hash {
+	if o == nil {
+		return 0
+	}
+	return o.Hash
+}
+
+// Signer is a SPHINCS-256 private key that implements crypto.Signer.
+type Signer struct {
+	privateKey *[PrivateKeySize]byte
+	publicKey  *PublicKey
+}
+
+// NewSigner returns a Signer for privateKey, which is not copied.
+func NewSigner(privateKey *[PrivateKeySize]byte) *Signer {
+	return &Signer{
+		privateKey: privateKey,
+		publicKey:  (*PublicKey)(PublicKeyFromPrivateKey(privateKey)),
+	}
+}
+
+// Public returns the *PublicKey corresponding to the private key.
+func (s *Signer) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs digest with the private key and returns the signature.  If opts
+// is nil or opts.HashFunc() is 0, digest is the unhashed message and a pure
+// signature is returned, as with Sign.  Otherwise digest is the output of the
+// digest algorithm and a pre-hashed signature is returned, as with
+// SignPrehashed.
+//
+// Signatures are deterministic, so rand is ignored.
+func (s *Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var h crypto.Hash
+	if opts != nil {
+		h = opts.HashFunc()
+	}
+
+	var sig *[SignatureSize]byte
+	if h == 0 {
+		sig = Sign(s.privateKey, digest)
+	} else {
+		var err error
+		if sig, err = SignPrehashed(s.privateKey, h, digest); err != nil {
+			return nil, err
+		}
+	}
+	return sig[:], nil
+}
+
+var _ crypto.Signer = (*Signer)(nil)
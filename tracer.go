@@ -0,0 +1,46 @@
+// tracer.go - SPHINCS-256 signing instrumentation hooks
+
+package sphincs256
+
+import (
+	"time"
+)
+
+// Tracer receives callbacks as a signature is computed, for finding where
+// signing time goes without a profiler.  The callbacks are invoked on the
+// signing goroutine, and a Tracer shared between concurrent signers must be
+// safe for concurrent use, which is why the elapsed times are passed in
+// rather than left to the Tracer to measure.
+//
+// Hash function invocation counts are process wide rather than per
+// signature, and are only available in builds with the
+// "sphincs256_hashcounters" tag, see hash.CountersEnabled and
+// hash.ReadCounters.
+type Tracer interface {
+	// OnSignStart is called before anything else is done for a signature.
+	OnSignStart()
+
+	// OnSignEnd is called once the signature is complete.
+	OnSignEnd(elapsed time.Duration)
+
+	// OnHORSTStart is called before the message hash is signed with HORST.
+	OnHORSTStart()
+
+	// OnHORSTEnd is called once the HORST signature is complete.
+	OnHORSTEnd(elapsed time.Duration)
+
+	// OnLayerStart is called before the hypertree layer level (0 is the
+	// bottom layer) is signed.
+	OnLayerStart(level int)
+
+	// OnLayerEnd is called once the WOTS signature and authentication path
+	// for the hypertree layer level are complete.
+	OnLayerEnd(level int, elapsed time.Duration)
+}
+
+func (o *SignOptions) tracer() Tracer {
+	if o == nil {
+		return nil
+	}
+	return o.Tracer
+}
@@ -0,0 +1,78 @@
+// tracer_test.go - SPHINCS-256 signing instrumentation tests
+
+package sphincs256
+
+import (
+	"crypto/rand"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yawning/sphincs256/hash"
+)
+
+type recordingTracer struct {
+	events []string
+}
+
+func (r *recordingTracer) OnSignStart() {
+	r.events = append(r.events, "sign start")
+}
+
+func (r *recordingTracer) OnSignEnd(elapsed time.Duration) {
+	r.events = append(r.events, "sign end")
+}
+
+func (r *recordingTracer) OnHORSTStart() {
+	r.events = append(r.events, "horst start")
+}
+
+func (r *recordingTracer) OnHORSTEnd(elapsed time.Duration) {
+	r.events = append(r.events, "horst end")
+}
+
+func (r *recordingTracer) OnLayerStart(level int) {
+	r.events = append(r.events, fmt.Sprintf("layer %d start", level))
+}
+
+func (r *recordingTracer) OnLayerEnd(level int, elapsed time.Duration) {
+	r.events = append(r.events, fmt.Sprintf("layer %d end", level))
+}
+
+func TestTracer(t *testing.T) {
+	const msg = "The Hounds of Tindalos."
+
+	_, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	expected := []string{"sign start", "horst start", "horst end"}
+	for i := 0; i < nLevels; i++ {
+		expected = append(expected, fmt.Sprintf("layer %d start", i), fmt.Sprintf("layer %d end", i))
+	}
+	expected = append(expected, "sign end")
+
+	before := hash.ReadCounters()
+
+	tr := new(recordingTracer)
+	sig := SignWithOptions(sk, []byte(msg), &SignOptions{Tracer: tr})
+	if !reflect.DeepEqual(tr.events, expected) {
+		t.Fatalf("unexpected events: %v", tr.events)
+	}
+	if *sig != *Sign(sk, []byte(msg)) {
+		t.Fatalf("SignWithOptions() with a Tracer does not match Sign()")
+	}
+
+	after := hash.ReadCounters()
+	if !hash.CountersEnabled {
+		if after != (hash.Counters{}) {
+			t.Fatalf("hash counters advanced while compiled out: %+v", after)
+		}
+		return
+	}
+	if after.Varlen <= before.Varlen || after.Hash_n_n <= before.Hash_n_n || after.Hash_2n_n <= before.Hash_2n_n {
+		t.Fatalf("hash counters did not advance: %+v -> %+v", before, after)
+	}
+}
@@ -4,10 +4,16 @@
 // algorithm.
 package utils
 
+import "runtime"
+
 // Zerobytes sets all the bytes in slice to 0x00.
 func Zerobytes(r []byte) []byte {
 	for i := 0; i < len(r); i++ {
 		r[i] = 0
 	}
+
+	// Ensure that the stores are not considered dead, even if r is never
+	// read from again.
+	runtime.KeepAlive(r)
 	return r
 }
@@ -0,0 +1,121 @@
+// verifier.go - SPHINCS-256 verification with a hypertree node cache
+
+package sphincs256
+
+import (
+	"container/list"
+	"crypto/subtle"
+	"sync"
+
+	"github.com/yawning/sphincs256/hash"
+)
+
+// DefaultVerifierCacheSize is the number of subtree roots a Verifier caches
+// if a non-positive size is passed to NewVerifier.
+const DefaultVerifierCacheSize = 1024
+
+// Verifier verifies signatures made with a single public key, caching the
+// roots of hypertree subtrees that have already been authenticated.
+//
+// Signatures from the same key share the upper layers of the hypertree (the
+// subtree at each level is selected by a prefix of the leaf index), so once a
+// subtree root has been proven all the way up to the public key root, any
+// later signature that reaches the same root for the same subtree can stop
+// climbing there.  Only roots from signatures that verified successfully are
+// ever cached.
+//
+// The remainder of the signature above a cached node is deterministic, and
+// is still required to match (by digest) what was previously authenticated,
+// so a Verifier accepts exactly the same signatures as Verify.
+//
+// A Verifier is safe for concurrent use.
+type Verifier struct {
+	publicKey [PublicKeySize]byte
+	cache     *nodeCache
+}
+
+// NewVerifier returns a Verifier bound to publicKey, that caches up to
+// cacheSize subtree roots.
+func NewVerifier(publicKey *[PublicKeySize]byte, cacheSize int) *Verifier {
+	if cacheSize <= 0 {
+		cacheSize = DefaultVerifierCacheSize
+	}
+	v := &Verifier{cache: newNodeCache(cacheSize)}
+	copy(v.publicKey[:], publicKey[:])
+	return v
+}
+
+// Verify takes a message and signature and returns true if the signature is
+// valid for the Verifier's public key.
+func (v *Verifier) Verify(message []byte, signature *[SignatureSize]byte) bool {
+	return verify(&v.publicKey, modePure, nil, message, signature, v.cache)
+}
+
+// nodeAddr identifies a subtree of the hypertree by its level and index.
+type nodeAddr struct {
+	level   int
+	subtree uint64
+}
+
+type nodeEntry struct {
+	addr nodeAddr
+	root [hash.Size]byte
+	tail [hash.Size]byte // Digest of the signature above the node.
+}
+
+// nodeCache is a bounded LRU cache of authenticated subtree roots.
+type nodeCache struct {
+	sync.Mutex
+
+	size    int
+	entries map[nodeAddr]*list.Element
+	lru     *list.List
+}
+
+func newNodeCache(size int) *nodeCache {
+	return &nodeCache{
+		size:    size,
+		entries: make(map[nodeAddr]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// lookup returns true iff an authenticated entry for n.addr is cached, and
+// matches n.
+func (c *nodeCache) lookup(n *nodeEntry) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[n.addr]
+	if !ok {
+		return false
+	}
+	cached := e.Value.(*nodeEntry)
+	if subtle.ConstantTimeCompare(cached.root[:], n.root[:])&subtle.ConstantTimeCompare(cached.tail[:], n.tail[:]) != 1 {
+		return false
+	}
+	c.lru.MoveToFront(e)
+	return true
+}
+
+// insert adds authenticated entries to the cache, evicting the least recently
+// used entries as needed.
+func (c *nodeCache) insert(nodes []nodeEntry) {
+	c.Lock()
+	defer c.Unlock()
+
+	for i := range nodes {
+		n := nodes[i]
+		if e, ok := c.entries[n.addr]; ok {
+			*e.Value.(*nodeEntry) = n
+			c.lru.MoveToFront(e)
+			continue
+		}
+		c.entries[n.addr] = c.lru.PushFront(&n)
+		for c.lru.Len() > c.size {
+			e := c.lru.Back()
+			delete(c.entries, e.Value.(*nodeEntry).addr)
+			c.lru.Remove(e)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// verifier_test.go - SPHINCS-256 Verifier tests
+
+package sphincs256
+
+import (
+	"crypto/rand"
+	"testing"
+)
+
+func TestVerifier(t *testing.T) {
+	msgs := []string{
+		"That is not dead which can eternal lie,",
+		"And with strange aeons even death may die.",
+	}
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+
+	v := NewVerifier(pk, 0)
+	for _, msg := range msgs {
+		sig := Sign(sk, []byte(msg))
+
+		// The first call populates the cache, the second hits it.
+		for i := 0; i < 2; i++ {
+			if !v.Verify([]byte(msg), sig) {
+				t.Fatalf("failed Verify() [%d]", i)
+			}
+		}
+		if v.cache.lru.Len() == 0 {
+			t.Fatalf("cache is empty after successful Verify()")
+		}
+
+		// A corrupted signature must not verify with a warm cache, even if
+		// the corruption is above the cached nodes.
+		sig[SignatureSize-1] ^= 0xa5
+		if v.Verify([]byte(msg), sig) {
+			t.Fatalf("Verify() accepted a corrupted signature")
+		}
+		sig[SignatureSize-1] ^= 0xa5
+
+		// Neither should a signature for a different message.
+		if v.Verify([]byte("Ph'nglui mglw'nafh Cthulhu R'lyeh wgah'nagl fhtagn."), sig) {
+			t.Fatalf("Verify() accepted a signature for the wrong message")
+		}
+	}
+
+	if v.cache.lru.Len() > 2*(nLevels-1) {
+		t.Errorf("cache has more entries than possible: %d", v.cache.lru.Len())
+	}
+}
+
+func TestVerifierNoFalseCache(t *testing.T) {
+	const msg = "The oldest and strongest emotion of mankind is fear."
+
+	pk, sk, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	sig := Sign(sk, []byte(msg))
+	sig[SignatureSize-1] ^= 0xa5
+
+	// Roots from signatures that fail verification must never be cached.
+	v := NewVerifier(pk, 0)
+	if v.Verify([]byte(msg), sig) {
+		t.Fatalf("Verify() accepted a corrupted signature")
+	}
+	if n := v.cache.lru.Len(); n != 0 {
+		t.Fatalf("cache populated by a failed Verify(): %d entries", n)
+	}
+}
+
+func TestNodeCacheEviction(t *testing.T) {
+	nodes := []nodeEntry{
+		{addr: nodeAddr{0, 1}},
+		{addr: nodeAddr{0, 2}},
+		{addr: nodeAddr{0, 3}},
+	}
+
+	c := newNodeCache(2)
+	c.insert(nodes)
+	if c.lru.Len() != 2 {
+		t.Fatalf("cache size: %d", c.lru.Len())
+	}
+	if c.lookup(&nodes[0]) {
+		t.Errorf("least recently used entry not evicted")
+	}
+	if !c.lookup(&nodes[2]) {
+		t.Errorf("most recently used entry evicted")
+	}
+}
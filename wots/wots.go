@@ -5,6 +5,8 @@ package wots
 import (
 	"github.com/yawning/sphincs256/chacha"
 	"github.com/yawning/sphincs256/hash"
+	"github.com/yawning/sphincs256/securemem"
+	"github.com/yawning/sphincs256/utils"
 )
 
 const (
@@ -20,6 +22,31 @@ const (
 	SigBytes = L * hash.Size
 )
 
+// Scratch is reusable working memory for Pkgen and Sign, holding the
+// expanded secret key in secure memory, since allocating it is comparatively
+// expensive.  The zero value is ready for use.  A Scratch must not be used
+// concurrently.
+type Scratch struct {
+	seedBuf *securemem.Buffer
+}
+
+// seeds returns the buffer for the expanded secret key, allocating it if
+// needed.
+func (s *Scratch) seeds() []byte {
+	if s.seedBuf == nil {
+		s.seedBuf = securemem.New(L * hash.Size)
+	}
+	return s.seedBuf.Bytes()
+}
+
+// Destroy releases the secure memory held by the Scratch.
+func (s *Scratch) Destroy() {
+	if s.seedBuf != nil {
+		s.seedBuf.Destroy()
+		s.seedBuf = nil
+	}
+}
+
 func expandSeed(outseeds []byte, inseed []byte) {
 //	outseeds = outseeds[:L*hash.Size]
 //	inseed = inseed[:SeedBytes]
@@ -37,21 +64,50 @@ func genChain(out, seed []byte, masks []byte, chainlen int) {
 	}
 }
 
-func Pkgen(pk []byte, sk []byte, masks []byte) {
+// Chain computes the i-th chain for the secret key seed sk iterated chainlen
+// times, without expanding the rest of the secret key.  The output is
+// identical to the i-th element of the output of Pkgen (for chainlen = W-1)
+// and Sign (for chainlen the i-th base W digit of the message and checksum).
+func Chain(out []byte, sk []byte, masks []byte, i, chainlen int) {
+//	out = out[:hash.Size]
+//	sk = sk[:SeedBytes]
+
+	var seed [hash.Size]byte
+	chacha.PrgAt(seed[:], sk[0:SeedBytes], uint64(i*hash.Size))
+	genChain(out, seed[:], masks, chainlen)
+	utils.Zerobytes(seed[:])
+}
+
+// Pkgen generates a WOTS public key, using scratch as working memory (if
+// scratch is nil, it is allocated).
+func Pkgen(pk []byte, sk []byte, masks []byte, scratch *Scratch) {
 //	pk = pk[:L*hash.Size]
 //	sk = sk[:SeedBytes]
 //	masks = masks[:(W-1)*hash.Size]
 
-	expandSeed(pk, sk)
+	if scratch == nil {
+		scratch = new(Scratch)
+		defer scratch.Destroy()
+	}
+	seeds := scratch.seeds()
+	expandSeed(seeds, sk)
 	for i := 0; i < L; i++ {
-		genChain(pk[i*hash.Size:], pk[i*hash.Size:], masks, W-1)
+		genChain(pk[i*hash.Size:], seeds[i*hash.Size:], masks, W-1)
 	}
+	scratch.seedBuf.Zero()
 }
 
-func Sign(sig []byte, msg *[hash.Size]byte, sk *[SeedBytes]byte, masks []byte) {
+// Sign generates a WOTS signature, using scratch as working memory (if
+// scratch is nil, it is allocated).
+func Sign(sig []byte, msg *[hash.Size]byte, sk *[SeedBytes]byte, masks []byte, scratch *Scratch) {
 //	sig = sig[:L*hash.Size]
 //	masks = masks[:(W-1)*hash.Size]
 
+	if scratch == nil {
+		scratch = new(Scratch)
+		defer scratch.Destroy()
+	}
+
 	var basew [L]int
 	var c, i int
 	switch W {
@@ -67,10 +123,7 @@ func Sign(sig []byte, msg *[hash.Size]byte, sk *[SeedBytes]byte, masks []byte) {
 			c >>= 4
 		}
 
-		expandSeed(sig, sk[:])
-		for i = 0; i < L; i++ {
-			genChain(sig[i*hash.Size:], sig[i*hash.Size:], masks, basew[i])
-		}
+		signChains(sig, sk, masks, &basew, scratch)
 	case 4:
 		for i = 0; i < L1; i += 4 {
 			basew[i] = int(msg[i/4] & 0x3)
@@ -87,15 +140,21 @@ func Sign(sig []byte, msg *[hash.Size]byte, sk *[SeedBytes]byte, masks []byte) {
 			c >>= 4
 		}
 
-		expandSeed(sig, sk[:])
-		for i = 0; i < L; i++ {
-			genChain(sig[i*hash.Size:], sig[i*hash.Size:], masks, basew[i])
-		}
+		signChains(sig, sk, masks, &basew, scratch)
 	default:
 		panic("not yet implemented")
 	}
 }
 
+func signChains(sig []byte, sk *[SeedBytes]byte, masks []byte, basew *[L]int, scratch *Scratch) {
+	seeds := scratch.seeds()
+	expandSeed(seeds, sk[:])
+	for i := 0; i < L; i++ {
+		genChain(sig[i*hash.Size:], seeds[i*hash.Size:], masks, basew[i])
+	}
+	scratch.seedBuf.Zero()
+}
+
 func Verify(pk *[L * hash.Size]byte, sig []byte, msg *[hash.Size]byte, masks []byte) {
 //	sig = sig[:L*hash.Size]
 //	masks = masks[:(W-1)*hash.Size]
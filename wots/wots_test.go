@@ -0,0 +1,137 @@
+// wots_test.go - WOTS tests
+
+package wots
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/yawning/sphincs256/hash"
+)
+
+func TestSignVerify(t *testing.T) {
+	var sk [SeedBytes]byte
+	var msg [hash.Size]byte
+	var masks [(W - 1) * hash.Size]byte
+	rand.Read(sk[:])
+	rand.Read(msg[:])
+	rand.Read(masks[:])
+
+	var pk, pk2 [L * hash.Size]byte
+	var sig [SigBytes]byte
+	Pkgen(pk[:], sk[:], masks[:], nil)
+	Sign(sig[:], &msg, &sk, masks[:], nil)
+	Verify(&pk2, sig[:], &msg, masks[:])
+	if pk != pk2 {
+		t.Fatalf("Verify() does not recover the public key")
+	}
+
+	var scratch Scratch
+	defer scratch.Destroy()
+	var pk3 [L * hash.Size]byte
+	var sig2 [SigBytes]byte
+	Pkgen(pk3[:], sk[:], masks[:], &scratch)
+	Sign(sig2[:], &msg, &sk, masks[:], &scratch)
+	if pk3 != pk || sig2 != sig {
+		t.Fatalf("output depends on the Scratch")
+	}
+	if seeds := scratch.seedBuf.Bytes(); !bytes.Equal(seeds, make([]byte, len(seeds))) {
+		t.Fatalf("Sign() left the expanded secret key intact")
+	}
+
+	msg[0] ^= 1
+	Verify(&pk2, sig[:], &msg, masks[:])
+	if pk == pk2 {
+		t.Fatalf("Verify() recovered the public key for the wrong message")
+	}
+}
+
+func TestChain(t *testing.T) {
+	var sk [SeedBytes]byte
+	var msg [hash.Size]byte
+	var masks [(W - 1) * hash.Size]byte
+	rand.Read(sk[:])
+	rand.Read(msg[:])
+	rand.Read(masks[:])
+
+	var pk [L * hash.Size]byte
+	var sig [SigBytes]byte
+	Pkgen(pk[:], sk[:], masks[:], nil)
+	Sign(sig[:], &msg, &sk, masks[:], nil)
+
+	// The base W digits of the message and checksum, as in Sign.
+	var basew [L]int
+	var c, i int
+	for i = 0; i < L1; i += 2 {
+		basew[i] = int(msg[i/2] & 0xf)
+		basew[i+1] = int(msg[i/2] >> 4)
+		c += W - 1 - basew[i]
+		c += W - 1 - basew[i+1]
+	}
+	for ; i < L; i++ {
+		basew[i] = c & 0xf
+		c >>= 4
+	}
+
+	var out [hash.Size]byte
+	for i := 0; i < L; i++ {
+		Chain(out[:], sk[:], masks[:], i, W-1)
+		if !bytes.Equal(out[:], pk[i*hash.Size:(i+1)*hash.Size]) {
+			t.Errorf("[%d]: Chain() does not match Pkgen()", i)
+		}
+		Chain(out[:], sk[:], masks[:], i, basew[i])
+		if !bytes.Equal(out[:], sig[i*hash.Size:(i+1)*hash.Size]) {
+			t.Errorf("[%d]: Chain() does not match Sign()", i)
+		}
+	}
+}
+
+func BenchmarkPkgen(b *testing.B) {
+	var sk [SeedBytes]byte
+	var masks [(W - 1) * hash.Size]byte
+	var pk [L * hash.Size]byte
+	rand.Read(sk[:])
+	rand.Read(masks[:])
+	scratch := new(Scratch)
+	defer scratch.Destroy()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Pkgen(pk[:], sk[:], masks[:], scratch)
+	}
+}
+
+func BenchmarkSign(b *testing.B) {
+	var sk [SeedBytes]byte
+	var msg [hash.Size]byte
+	var masks [(W - 1) * hash.Size]byte
+	var sig [SigBytes]byte
+	rand.Read(sk[:])
+	rand.Read(msg[:])
+	rand.Read(masks[:])
+	scratch := new(Scratch)
+	defer scratch.Destroy()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Sign(sig[:], &msg, &sk, masks[:], scratch)
+	}
+}
+
+func BenchmarkVerify(b *testing.B) {
+	var sk [SeedBytes]byte
+	var msg [hash.Size]byte
+	var masks [(W - 1) * hash.Size]byte
+	var sig [SigBytes]byte
+	var pk [L * hash.Size]byte
+	rand.Read(sk[:])
+	rand.Read(msg[:])
+	rand.Read(masks[:])
+	Sign(sig[:], &msg, &sk, masks[:], nil)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Verify(&pk, sig[:], &msg, masks[:])
+	}
+}
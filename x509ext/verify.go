@@ -0,0 +1,166 @@
+// verify.go - SPHINCS-256 X.509 certificate chain verification
+
+package x509ext
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// maxChainLength is the maximum number of certificates in a chain,
+	// including the leaf and the root.
+	maxChainLength = 8
+
+	// maxSignatureChecks is the maximum number of signatures checked while
+	// building a chain, since each check is comparatively expensive.
+	maxSignatureChecks = 100
+)
+
+var (
+	// ErrNoValidChain is the error returned when a certificate does not
+	// chain to any of the roots.
+	ErrNoValidChain = errors.New("x509ext: no valid chain to a trusted root")
+
+	// ErrTooManySignatureChecks is the error returned when building a chain
+	// gives up after checking maxSignatureChecks signatures.
+	ErrTooManySignatureChecks = errors.New("x509ext: signature check limit reached while building a chain")
+)
+
+// VerifyOptions are the parameters for Certificate.Verify.
+type VerifyOptions struct {
+	// Roots are the trusted root certificates.
+	Roots []*Certificate
+
+	// Intermediates are the untrusted certificates that may be used to
+	// build a chain from the leaf to a root.
+	Intermediates []*Certificate
+
+	// CurrentTime is the time at which the certificates must be valid.  If
+	// zero, the current time is used.
+	CurrentTime time.Time
+}
+
+// Verify verifies c by building a chain of valid signatures from c to one of
+// opts.Roots, via opts.Intermediates, and returns the chain, starting with c
+// and ending with the root.  Every certificate in the chain must be valid
+// at opts.CurrentTime, every certificate but c must be a CA certificate, and
+// the number of intermediates below each CA certificate must be within its
+// MaxPathLen.  A nil opts is treated as the zero VerifyOptions.
+func (c *Certificate) Verify(opts *VerifyOptions) ([]*Certificate, error) {
+	if opts == nil {
+		opts = new(VerifyOptions)
+	}
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if err := checkValidity(c, now); err != nil {
+		return nil, err
+	}
+	for _, root := range opts.Roots {
+		if bytes.Equal(c.Raw, root.Raw) {
+			return []*Certificate{c}, nil
+		}
+	}
+
+	b := &chainBuilder{opts: opts, now: now, checked: make(map[[2]*Certificate]bool)}
+	return b.buildChain([]*Certificate{c})
+}
+
+// chainBuilder holds the state of a single Verify call.
+type chainBuilder struct {
+	opts *VerifyOptions
+	now  time.Time
+
+	// checked memoizes the signature checks, keyed by the certificate and
+	// the issuer, since the depth first search can reach the same pair
+	// through different paths.
+	checked map[[2]*Certificate]bool
+	nChecks int
+}
+
+// buildChain extends chain, a valid chain from the leaf to its last
+// certificate, until it ends with a root, depth first.
+func (b *chainBuilder) buildChain(chain []*Certificate) ([]*Certificate, error) {
+	c := chain[len(chain)-1]
+	for _, root := range b.opts.Roots {
+		if ok, err := b.isIssuer(chain, root); err != nil {
+			return nil, err
+		} else if ok {
+			return append(chain, root), nil
+		}
+	}
+
+	// Only root certificates may be self-signed, and chains can't be
+	// arbitrarily long.
+	if bytes.Equal(c.RawIssuer, c.RawSubject) || len(chain)+1 >= maxChainLength {
+		return nil, ErrNoValidChain
+	}
+
+nextIntermediate:
+	for _, ca := range b.opts.Intermediates {
+		for _, prev := range chain {
+			if bytes.Equal(prev.Raw, ca.Raw) {
+				continue nextIntermediate
+			}
+		}
+		if ok, err := b.isIssuer(chain, ca); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		ret, err := b.buildChain(append(chain[:len(chain):len(chain)], ca))
+		if err != ErrNoValidChain {
+			return ret, err
+		}
+	}
+	return nil, ErrNoValidChain
+}
+
+// isIssuer returns true iff ca issued the last certificate of chain, and
+// may do so with the number of intermediates already in chain.
+func (b *chainBuilder) isIssuer(chain []*Certificate, ca *Certificate) (bool, error) {
+	c := chain[len(chain)-1]
+	if !isCandidateIssuer(c, ca, b.now) {
+		return false, nil
+	}
+
+	// Every certificate in chain but the leaf is an intermediate below ca.
+	if ca.MaxPathLen >= 0 && len(chain)-1 > ca.MaxPathLen {
+		return false, nil
+	}
+
+	key := [2]*Certificate{c, ca}
+	if ok, seen := b.checked[key]; seen {
+		return ok, nil
+	}
+	if b.nChecks >= maxSignatureChecks {
+		return false, ErrTooManySignatureChecks
+	}
+	b.nChecks++
+	ok := c.CheckSignatureFrom(ca) == nil
+	b.checked[key] = ok
+	return ok, nil
+}
+
+// isCandidateIssuer returns true iff ca could have issued c, based on the
+// names, key identifiers and validity, but not the signature.
+func isCandidateIssuer(c, ca *Certificate, now time.Time) bool {
+	if !bytes.Equal(c.RawIssuer, ca.RawSubject) {
+		return false
+	}
+	if len(c.AuthorityKeyID) > 0 && len(ca.SubjectKeyID) > 0 && !bytes.Equal(c.AuthorityKeyID, ca.SubjectKeyID) {
+		return false
+	}
+	return checkValidity(ca, now) == nil
+}
+
+func checkValidity(c *Certificate, now time.Time) error {
+	if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+		return fmt.Errorf("x509ext: certificate %q is not valid at %v", c.Subject.CommonName, now.UTC())
+	}
+	return nil
+}
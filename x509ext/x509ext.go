@@ -0,0 +1,385 @@
+// x509ext.go - X.509 certificates with SPHINCS-256 signatures
+
+// Package x509ext implements issuing, parsing and verifying X.509
+// certificates with SPHINCS-256 keys and signatures, which crypto/x509 does
+// not support.
+//
+// Certificates use OIDSPHINCS256 both as the subject public key algorithm
+// and the signature algorithm, with absent parameters, like Ed25519
+// (RFC 8410).  The public key is the raw SPHINCS-256 public key, and the
+// signature is the pure SPHINCS-256 signature over the DER encoded
+// TBSCertificate.
+//
+// There is no registered OID for SPHINCS-256, so OIDSPHINCS256 is an
+// experimental one, under the arc reserved for documentation by RFC 5612.
+// Certificates issued with it are only suitable for testing and private
+// PKIs.
+package x509ext
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/yawning/sphincs256"
+)
+
+// OIDSPHINCS256 is the experimental SPHINCS-256 public key and signature
+// algorithm OID.
+var OIDSPHINCS256 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 32473, 1, 256}
+
+var (
+	oidExtensionSubjectKeyID     = asn1.ObjectIdentifier{2, 5, 29, 14}
+	oidExtensionKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidExtensionAuthorityKeyID   = asn1.ObjectIdentifier{2, 5, 29, 35}
+)
+
+// ErrNotSPHINCS256 is the error returned when parsing a certificate that does
+// not have a SPHINCS-256 public key and signature.
+var ErrNotSPHINCS256 = errors.New("x509ext: not a SPHINCS-256 certificate")
+
+type certificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type tbsCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           validity
+	Subject            asn1.RawValue
+	PublicKey          publicKeyInfo
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+type validity struct {
+	NotBefore, NotAfter time.Time
+}
+
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type basicConstraints struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+type authorityKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
+// Template is the contents of a certificate to be issued by
+// CreateCertificate.
+type Template struct {
+	SerialNumber        *big.Int
+	Subject             pkix.Name
+	NotBefore, NotAfter time.Time
+
+	// IsCA is set for CA certificates, which may sign other certificates.
+	IsCA bool
+
+	// MaxPathLen is the maximum number of intermediate CA certificates that
+	// may follow a CA certificate in a chain.  As with crypto/x509, 0 is
+	// treated as unset (no limit) unless MaxPathLenZero is set, and -1 is
+	// always unset.  It is only encoded for CA certificates.
+	MaxPathLen     int
+	MaxPathLenZero bool
+
+	// KeyUsage is the key usage.  If 0, x509.KeyUsageCertSign is used for
+	// CA certificates, and x509.KeyUsageDigitalSignature otherwise.
+	KeyUsage x509.KeyUsage
+}
+
+// Certificate is a parsed SPHINCS-256 X.509 certificate.
+type Certificate struct {
+	Raw               []byte // The complete DER encoded certificate.
+	RawTBSCertificate []byte // The DER encoded TBSCertificate, which is signed.
+	RawSubject        []byte
+	RawIssuer         []byte
+
+	SerialNumber        *big.Int
+	Subject, Issuer     pkix.Name
+	NotBefore, NotAfter time.Time
+
+	PublicKey *[sphincs256.PublicKeySize]byte
+	Signature *[sphincs256.SignatureSize]byte
+
+	IsCA           bool
+	MaxPathLen     int           // -1 if there is no limit.
+	KeyUsage       x509.KeyUsage // 0 if there is no key usage extension.
+	SubjectKeyID   []byte
+	AuthorityKeyID []byte
+}
+
+// CreateCertificate issues a certificate for publicKey as per template, and
+// returns it DER encoded.  If parent is nil, the certificate is self-signed,
+// and privateKey must correspond to publicKey.  Otherwise the certificate is
+// signed by parent, a CA certificate, and privateKey must correspond to the
+// parent's public key.
+func CreateCertificate(template *Template, publicKey *[sphincs256.PublicKeySize]byte, parent *Certificate, privateKey *[sphincs256.PrivateKeySize]byte) ([]byte, error) {
+	if template.SerialNumber == nil {
+		return nil, errors.New("x509ext: no serial number")
+	}
+	if template.SerialNumber.Sign() < 0 {
+		return nil, errors.New("x509ext: negative serial number")
+	}
+
+	subject, err := asn1.Marshal(template.Subject.ToRDNSequence())
+	if err != nil {
+		return nil, err
+	}
+	subjectKeyID := keyID(publicKey)
+	issuer, issuerKey, authorityKeyID := subject, publicKey, subjectKeyID
+	if parent != nil {
+		if !parent.canSign() {
+			return nil, errors.New("x509ext: parent is not a CA certificate")
+		}
+		issuer, issuerKey, authorityKeyID = parent.RawSubject, parent.PublicKey, parent.SubjectKeyID
+	}
+
+	exts, err := buildExtensions(template, subjectKeyID, authorityKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	algo := pkix.AlgorithmIdentifier{Algorithm: OIDSPHINCS256}
+	tbs := tbsCertificate{
+		Version:            2,
+		SerialNumber:       template.SerialNumber,
+		SignatureAlgorithm: algo,
+		Issuer:             asn1.RawValue{FullBytes: issuer},
+		Validity:           validity{template.NotBefore.UTC().Truncate(time.Second), template.NotAfter.UTC().Truncate(time.Second)},
+		Subject:            asn1.RawValue{FullBytes: subject},
+		PublicKey: publicKeyInfo{
+			Algorithm: algo,
+			PublicKey: asn1.BitString{Bytes: publicKey[:], BitLength: 8 * len(publicKey)},
+		},
+		Extensions: exts,
+	}
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := sphincs256.Sign(privateKey, tbsDER)
+	if !sphincs256.Verify(issuerKey, tbsDER, sig) {
+		return nil, errors.New("x509ext: private key does not match the issuer public key")
+	}
+
+	return asn1.Marshal(certificate{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbsDER},
+		SignatureAlgorithm: algo,
+		SignatureValue:     asn1.BitString{Bytes: sig[:], BitLength: 8 * len(sig)},
+	})
+}
+
+func buildExtensions(template *Template, subjectKeyID, authKeyID []byte) ([]pkix.Extension, error) {
+	keyUsage := template.KeyUsage
+	if keyUsage == 0 {
+		keyUsage = x509.KeyUsageDigitalSignature
+		if template.IsCA {
+			keyUsage = x509.KeyUsageCertSign
+		}
+	}
+
+	var exts []pkix.Extension
+	add := func(oid asn1.ObjectIdentifier, critical bool, v interface{}) error {
+		b, err := asn1.Marshal(v)
+		if err != nil {
+			return err
+		}
+		exts = append(exts, pkix.Extension{Id: oid, Critical: critical, Value: b})
+		return nil
+	}
+	if err := add(oidExtensionKeyUsage, true, marshalKeyUsage(keyUsage)); err != nil {
+		return nil, err
+	}
+	maxPathLen := -1
+	if template.IsCA && (template.MaxPathLen > 0 || (template.MaxPathLen == 0 && template.MaxPathLenZero)) {
+		maxPathLen = template.MaxPathLen
+	}
+	if err := add(oidExtensionBasicConstraints, true, basicConstraints{IsCA: template.IsCA, MaxPathLen: maxPathLen}); err != nil {
+		return nil, err
+	}
+	if err := add(oidExtensionSubjectKeyID, false, subjectKeyID); err != nil {
+		return nil, err
+	}
+	if err := add(oidExtensionAuthorityKeyID, false, authorityKeyID{ID: authKeyID}); err != nil {
+		return nil, err
+	}
+	return exts, nil
+}
+
+// ParseCertificate parses a DER encoded SPHINCS-256 certificate.  The
+// signature is not verified.
+func ParseCertificate(der []byte) (*Certificate, error) {
+	var cert certificate
+	if rest, err := asn1.Unmarshal(der, &cert); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509ext: trailing data after certificate")
+	}
+	var tbs tbsCertificate
+	if rest, err := asn1.Unmarshal(cert.TBSCertificate.FullBytes, &tbs); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("x509ext: trailing data after TBSCertificate")
+	}
+
+	if !isSPHINCS256(&cert.SignatureAlgorithm) || !isSPHINCS256(&tbs.SignatureAlgorithm) || !isSPHINCS256(&tbs.PublicKey.Algorithm) {
+		return nil, ErrNotSPHINCS256
+	}
+	if tbs.Version != 2 {
+		return nil, fmt.Errorf("x509ext: unsupported certificate version %d", tbs.Version+1)
+	}
+	if len(cert.SignatureValue.Bytes) != sphincs256.SignatureSize || cert.SignatureValue.BitLength != 8*sphincs256.SignatureSize {
+		return nil, errors.New("x509ext: invalid signature size")
+	}
+	if len(tbs.PublicKey.PublicKey.Bytes) != sphincs256.PublicKeySize || tbs.PublicKey.PublicKey.BitLength != 8*sphincs256.PublicKeySize {
+		return nil, errors.New("x509ext: invalid public key size")
+	}
+
+	c := &Certificate{
+		Raw:               der,
+		RawTBSCertificate: tbs.Raw,
+		RawSubject:        tbs.Subject.FullBytes,
+		RawIssuer:         tbs.Issuer.FullBytes,
+		SerialNumber:      tbs.SerialNumber,
+		NotBefore:         tbs.Validity.NotBefore,
+		NotAfter:          tbs.Validity.NotAfter,
+		PublicKey:         new([sphincs256.PublicKeySize]byte),
+		Signature:         new([sphincs256.SignatureSize]byte),
+		MaxPathLen:        -1,
+	}
+	copy(c.PublicKey[:], tbs.PublicKey.PublicKey.Bytes)
+	copy(c.Signature[:], cert.SignatureValue.Bytes)
+	if err := parseName(&c.Subject, c.RawSubject); err != nil {
+		return nil, err
+	}
+	if err := parseName(&c.Issuer, c.RawIssuer); err != nil {
+		return nil, err
+	}
+	if err := c.parseExtensions(tbs.Extensions); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Certificate) parseExtensions(exts []pkix.Extension) error {
+	for _, ext := range exts {
+		var err error
+		switch {
+		case ext.Id.Equal(oidExtensionKeyUsage):
+			var bs asn1.BitString
+			if err = unmarshalExtension(ext.Value, &bs); err == nil {
+				for i := 0; i < bs.BitLength && i < 9; i++ {
+					if bs.At(i) != 0 {
+						c.KeyUsage |= 1 << uint(i)
+					}
+				}
+			}
+		case ext.Id.Equal(oidExtensionBasicConstraints):
+			var bc basicConstraints
+			if err = unmarshalExtension(ext.Value, &bc); err == nil {
+				c.IsCA, c.MaxPathLen = bc.IsCA, bc.MaxPathLen
+				if c.MaxPathLen < -1 {
+					err = errors.New("negative path length")
+				}
+			}
+		case ext.Id.Equal(oidExtensionSubjectKeyID):
+			err = unmarshalExtension(ext.Value, &c.SubjectKeyID)
+		case ext.Id.Equal(oidExtensionAuthorityKeyID):
+			var aki authorityKeyID
+			if err = unmarshalExtension(ext.Value, &aki); err == nil {
+				c.AuthorityKeyID = aki.ID
+			}
+		default:
+			if ext.Critical {
+				return fmt.Errorf("x509ext: unsupported critical extension %v", ext.Id)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("x509ext: malformed extension %v: %v", ext.Id, err)
+		}
+	}
+	return nil
+}
+
+// CheckSignatureFrom returns nil iff c is signed by parent, which must be a
+// CA certificate.  Validity periods are not checked.
+func (c *Certificate) CheckSignatureFrom(parent *Certificate) error {
+	if !parent.canSign() {
+		return errors.New("x509ext: parent is not a CA certificate")
+	}
+	if !bytes.Equal(c.RawIssuer, parent.RawSubject) {
+		return errors.New("x509ext: issuer does not match the parent subject")
+	}
+	if !sphincs256.Verify(parent.PublicKey, c.RawTBSCertificate, c.Signature) {
+		return errors.New("x509ext: invalid signature")
+	}
+	return nil
+}
+
+// canSign returns true iff c may sign certificates, that is if it is a CA
+// certificate, and its key usage permits certificate signing.  As with
+// crypto/x509, a certificate without a key usage extension may be used for
+// any purpose.
+func (c *Certificate) canSign() bool {
+	return c.IsCA && (c.KeyUsage == 0 || c.KeyUsage&x509.KeyUsageCertSign != 0)
+}
+
+func isSPHINCS256(algo *pkix.AlgorithmIdentifier) bool {
+	return algo.Algorithm.Equal(OIDSPHINCS256) && len(algo.Parameters.FullBytes) == 0
+}
+
+// keyID returns the subject key identifier for publicKey, the raw bytes of
+// its sphincs256.KeyID.
+func keyID(publicKey *[sphincs256.PublicKeySize]byte) []byte {
+	id := (*sphincs256.PublicKey)(publicKey).KeyID()
+	return id[:]
+}
+
+func parseName(name *pkix.Name, der []byte) error {
+	var rdns pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(der, &rdns); err != nil {
+		return err
+	} else if len(rest) != 0 {
+		return errors.New("x509ext: trailing data after name")
+	}
+	name.FillFromRDNSequence(&rdns)
+	return nil
+}
+
+func unmarshalExtension(der []byte, v interface{}) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err == nil && len(rest) != 0 {
+		err = errors.New("trailing data")
+	}
+	return err
+}
+
+// marshalKeyUsage encodes the key usage as a bit string, where bit 0 is the
+// most significant bit of the first byte, and trailing zero bits are omitted.
+func marshalKeyUsage(ku x509.KeyUsage) asn1.BitString {
+	var b [2]byte
+	bitLength := 0
+	for i := 0; i < 9; i++ {
+		if ku&(1<<uint(i)) != 0 {
+			b[i/8] |= 0x80 >> uint(i%8)
+			bitLength = i + 1
+		}
+	}
+	return asn1.BitString{Bytes: b[:(bitLength+7)/8], BitLength: bitLength}
+}
@@ -0,0 +1,270 @@
+// x509ext_test.go - SPHINCS-256 X.509 certificate tests
+
+package x509ext
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/yawning/sphincs256"
+)
+
+type testIdentity struct {
+	pk   *[sphincs256.PublicKeySize]byte
+	sk   *[sphincs256.PrivateKeySize]byte
+	cert *Certificate
+}
+
+func newTestIdentity(t *testing.T, name string, isCA bool, parent *testIdentity, notBefore, notAfter time.Time) *testIdentity {
+	pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey(): %s", err)
+	}
+	tmpl := &Template{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{"Miskatonic University"}},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		IsCA:         isCA,
+	}
+	return newTestIdentityFromTemplate(t, tmpl, pk, sk, parent)
+}
+
+func newTestIdentityFromTemplate(t *testing.T, tmpl *Template, pk *[sphincs256.PublicKeySize]byte, sk *[sphincs256.PrivateKeySize]byte, parent *testIdentity) *testIdentity {
+	name := tmpl.Subject.CommonName
+	id := &testIdentity{pk: pk, sk: sk}
+
+	var parentCert *Certificate
+	signingKey := sk
+	if parent != nil {
+		parentCert, signingKey = parent.cert, parent.sk
+	}
+	der, err := CreateCertificate(tmpl, pk, parentCert, signingKey)
+	if err != nil {
+		t.Fatalf("%s: failed CreateCertificate(): %s", name, err)
+	}
+	if id.cert, err = ParseCertificate(der); err != nil {
+		t.Fatalf("%s: failed ParseCertificate(): %s", name, err)
+	}
+	return id
+}
+
+func TestCertificateChain(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(24*time.Hour)
+
+	root := newTestIdentity(t, "Root CA", true, nil, notBefore, notAfter)
+	inter := newTestIdentity(t, "Intermediate CA", true, root, notBefore, notAfter)
+	leaf := newTestIdentity(t, "leaf.example", false, inter, notBefore, notAfter)
+
+	if !root.cert.IsCA || root.cert.KeyUsage != x509.KeyUsageCertSign || root.cert.MaxPathLen != -1 {
+		t.Fatalf("unexpected root CA constraints: %v %v %d", root.cert.IsCA, root.cert.KeyUsage, root.cert.MaxPathLen)
+	}
+	if leaf.cert.IsCA || leaf.cert.KeyUsage != x509.KeyUsageDigitalSignature {
+		t.Fatalf("unexpected leaf constraints: %v %v", leaf.cert.IsCA, leaf.cert.KeyUsage)
+	}
+	if *leaf.cert.PublicKey != *leaf.pk || leaf.cert.Issuer.CommonName != "Intermediate CA" {
+		t.Fatalf("unexpected leaf contents: %+v", leaf.cert)
+	}
+	if id := (*sphincs256.PublicKey)(leaf.pk).KeyID(); string(leaf.cert.SubjectKeyID) != string(id[:]) {
+		t.Fatalf("subject key ID is not the key ID")
+	}
+	if string(leaf.cert.AuthorityKeyID) != string(inter.cert.SubjectKeyID) {
+		t.Fatalf("authority key ID does not match the issuer")
+	}
+	if err := root.cert.CheckSignatureFrom(root.cert); err != nil {
+		t.Fatalf("invalid root self-signature: %s", err)
+	}
+
+	// The certificates should be well formed enough for crypto/x509.
+	stdCert, err := x509.ParseCertificate(leaf.cert.Raw)
+	if err != nil {
+		t.Fatalf("crypto/x509 failed to parse the certificate: %s", err)
+	}
+	if stdCert.Subject.CommonName != "leaf.example" || string(stdCert.RawTBSCertificate) != string(leaf.cert.RawTBSCertificate) {
+		t.Fatalf("crypto/x509 parsed a different certificate")
+	}
+
+	opts := &VerifyOptions{
+		Roots:         []*Certificate{root.cert},
+		Intermediates: []*Certificate{leaf.cert, inter.cert},
+	}
+	chain, err := leaf.cert.Verify(opts)
+	if err != nil {
+		t.Fatalf("failed Verify(): %s", err)
+	}
+	if len(chain) != 3 || chain[0] != leaf.cert || chain[1] != inter.cert || chain[2] != root.cert {
+		t.Fatalf("unexpected chain: %v", chain)
+	}
+	if chain, err = root.cert.Verify(opts); err != nil || len(chain) != 1 {
+		t.Fatalf("failed Verify() of the root: %v", err)
+	}
+
+	// Missing intermediate.
+	if _, err = leaf.cert.Verify(&VerifyOptions{Roots: opts.Roots}); err != ErrNoValidChain {
+		t.Fatalf("Verify() without the intermediate returned: %v", err)
+	}
+
+	// A nil opts is the zero VerifyOptions, which has no roots.
+	if _, err = leaf.cert.Verify(nil); err != ErrNoValidChain {
+		t.Fatalf("Verify() with nil options returned: %v", err)
+	}
+
+	// Untrusted root.
+	other := newTestIdentity(t, "Root CA", true, nil, notBefore, notAfter)
+	if _, err = leaf.cert.Verify(&VerifyOptions{Roots: []*Certificate{other.cert}, Intermediates: opts.Intermediates}); err != ErrNoValidChain {
+		t.Fatalf("Verify() with an untrusted root returned: %v", err)
+	}
+
+	// Expired.
+	if _, err = leaf.cert.Verify(&VerifyOptions{Roots: opts.Roots, Intermediates: opts.Intermediates, CurrentTime: notAfter.Add(time.Hour)}); err == nil {
+		t.Fatalf("Verify() accepted an expired chain")
+	}
+
+	// Tampered signature.
+	tampered := *leaf.cert
+	tampered.Signature = new([sphincs256.SignatureSize]byte)
+	*tampered.Signature = *leaf.cert.Signature
+	tampered.Signature[42] ^= 1
+	if _, err = tampered.Verify(opts); err != ErrNoValidChain {
+		t.Fatalf("Verify() with a tampered signature returned: %v", err)
+	}
+
+	// Leaf certificates can't issue certificates.
+	tmpl := &Template{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "evil"}, NotBefore: notBefore, NotAfter: notAfter}
+	if _, err = CreateCertificate(tmpl, leaf.pk, leaf.cert, leaf.sk); err == nil {
+		t.Fatalf("CreateCertificate() accepted a leaf as the parent")
+	}
+
+	// As with crypto/x509, a CA certificate without a key usage extension
+	// may issue certificates, but not one whose key usage excludes it.
+	noKeyUsage := *inter.cert
+	noKeyUsage.KeyUsage = 0
+	if err = leaf.cert.CheckSignatureFrom(&noKeyUsage); err != nil {
+		t.Fatalf("CheckSignatureFrom() rejected a CA without key usage: %s", err)
+	}
+	if _, err = CreateCertificate(tmpl, leaf.pk, &noKeyUsage, inter.sk); err != nil {
+		t.Fatalf("CreateCertificate() rejected a CA without key usage: %s", err)
+	}
+	noCertSign := *inter.cert
+	noCertSign.KeyUsage = x509.KeyUsageDigitalSignature
+	if err = leaf.cert.CheckSignatureFrom(&noCertSign); err == nil {
+		t.Fatalf("CheckSignatureFrom() accepted a CA without the cert sign key usage")
+	}
+	if _, err = CreateCertificate(tmpl, leaf.pk, &noCertSign, inter.sk); err == nil {
+		t.Fatalf("CreateCertificate() accepted a CA without the cert sign key usage")
+	}
+
+	// The signing key must match the parent.
+	if _, err = CreateCertificate(tmpl, leaf.pk, inter.cert, root.sk); err == nil {
+		t.Fatalf("CreateCertificate() accepted a mismatched signing key")
+	}
+}
+
+func TestMaxPathLen(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(24*time.Hour)
+
+	newCA := func(name string, maxPathLen int, parent *testIdentity) *testIdentity {
+		pk, sk, err := sphincs256.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatalf("failed GenerateKey(): %s", err)
+		}
+		tmpl := &Template{
+			SerialNumber:   big.NewInt(time.Now().UnixNano()),
+			Subject:        pkix.Name{CommonName: name},
+			NotBefore:      notBefore,
+			NotAfter:       notAfter,
+			IsCA:           true,
+			MaxPathLen:     maxPathLen,
+			MaxPathLenZero: true,
+		}
+		return newTestIdentityFromTemplate(t, tmpl, pk, sk, parent)
+	}
+
+	root := newCA("Root CA", 0, nil)
+	if root.cert.MaxPathLen != 0 {
+		t.Fatalf("unexpected MaxPathLen: %d", root.cert.MaxPathLen)
+	}
+	inter := newCA("Intermediate CA", -1, root)
+	if inter.cert.MaxPathLen != -1 {
+		t.Fatalf("unexpected MaxPathLen without a limit: %d", inter.cert.MaxPathLen)
+	}
+	leaf := newTestIdentity(t, "leaf.example", false, inter, notBefore, notAfter)
+	direct := newTestIdentity(t, "direct.example", false, root, notBefore, notAfter)
+
+	opts := &VerifyOptions{
+		Roots:         []*Certificate{root.cert},
+		Intermediates: []*Certificate{inter.cert},
+	}
+	if _, err := direct.cert.Verify(opts); err != nil {
+		t.Fatalf("failed Verify() without intermediates: %s", err)
+	}
+	if _, err := leaf.cert.Verify(opts); err != ErrNoValidChain {
+		t.Fatalf("Verify() past the root's MaxPathLen returned: %v", err)
+	}
+}
+
+func TestSignatureCheckLimit(t *testing.T) {
+	now := time.Now()
+	notBefore, notAfter := now.Add(-time.Hour), now.Add(24*time.Hour)
+
+	root := newTestIdentity(t, "Root CA", true, nil, notBefore, notAfter)
+	inter := newTestIdentity(t, "Intermediate CA", true, root, notBefore, notAfter)
+	leaf := newTestIdentity(t, "leaf.example", false, inter, notBefore, notAfter)
+	impostor := newTestIdentity(t, "Intermediate CA", true, root, notBefore, notAfter)
+
+	// Repeated signature checks are memoized.
+	b := &chainBuilder{opts: &VerifyOptions{}, now: now, checked: make(map[[2]*Certificate]bool)}
+	for i := 0; i < 2; i++ {
+		if ok, err := b.isIssuer([]*Certificate{leaf.cert}, inter.cert); !ok || err != nil {
+			t.Fatalf("isIssuer(): %v, %v", ok, err)
+		}
+	}
+	if b.nChecks != 1 {
+		t.Fatalf("signature checked %d times", b.nChecks)
+	}
+
+	// Many plausible, but wrong, issuers give up at the limit.
+	opts := &VerifyOptions{Roots: []*Certificate{root.cert}}
+	for i := 0; i <= maxSignatureChecks; i++ {
+		c := *impostor.cert
+		c.SubjectKeyID = nil
+		opts.Intermediates = append(opts.Intermediates, &c)
+	}
+	opts.Intermediates = append(opts.Intermediates, inter.cert)
+	if _, err := leaf.cert.Verify(opts); err != ErrTooManySignatureChecks {
+		t.Fatalf("Verify() with too many candidate issuers returned: %v", err)
+	}
+}
+
+func TestParseCertificateRejectsOtherAlgorithms(t *testing.T) {
+	// A minimal Ed25519 self-signed certificate from crypto/x509.
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Ed25519"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	pub, priv := mustEd25519Key(t)
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+	if err != nil {
+		t.Fatalf("failed x509.CreateCertificate(): %s", err)
+	}
+	if _, err = ParseCertificate(der); err != ErrNotSPHINCS256 {
+		t.Fatalf("ParseCertificate() of an Ed25519 certificate returned: %v", err)
+	}
+}
+
+func mustEd25519Key(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed ed25519.GenerateKey(): %s", err)
+	}
+	return pub, priv
+}